@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/officiallysidsingh/go-notify/config"
+	"github.com/officiallysidsingh/go-notify/internal/auth"
+	"github.com/officiallysidsingh/go-notify/internal/repository"
+	"github.com/officiallysidsingh/go-notify/internal/tenant"
+)
+
+// Creates or revokes API keys:
+//
+//	apikey -name billing-service -scopes "notifications:send notifications:read"
+//	apikey -name billing-service -revoke
+func main() {
+	name := flag.String("name", "", "Key name, reported as the caller")
+	tenantID := flag.String("tenant", tenant.Default, "Tenant the key is bound to")
+	scopes := flag.String("scopes", auth.ScopeSend, "Space separated scopes")
+	revoke := flag.Bool("revoke", false, "Revoke the named key instead of creating one")
+	flag.Parse()
+
+	if *name == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Load configuration from the config folder
+	config.LoadConfig("./config")
+
+	// Only the key is printed, so the database logs nothing
+	dbConn, err := repository.NewDB(config.AppConfig.Postgres, zap.NewNop())
+	if err != nil {
+		log.Fatalf("Failed to initialize PostgresDB: %v", err)
+	}
+
+	defer func() {
+		if err := dbConn.Close(); err != nil {
+			log.Printf("error closing dbConn: %v", err)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if *revoke {
+		revoked, err := dbConn.RevokeAPIKey(ctx, *tenantID, *name)
+		if err != nil {
+			log.Fatalf("Failed to revoke api key: %v", err)
+		}
+		fmt.Printf("Revoked %d key(s) named %s\n", revoked, *name)
+		return
+	}
+
+	key, err := auth.GenerateAPIKey()
+	if err != nil {
+		log.Fatalf("Failed to generate api key: %v", err)
+	}
+	if _, err := dbConn.InsertAPIKey(ctx, *tenantID, *name, auth.HashAPIKey(key), auth.ParseScopes(*scopes)); err != nil {
+		log.Fatalf("Failed to store api key: %v", err)
+	}
+
+	// The key is not stored, so this is the only time it can be shown
+	fmt.Println(key)
+}
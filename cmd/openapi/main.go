@@ -0,0 +1,23 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+
+	"go.uber.org/zap"
+
+	pb "github.com/officiallysidsingh/go-notify/api/generated"
+	"github.com/officiallysidsingh/go-notify/internal/gateway"
+)
+
+// Writes the HTTP gateway's OpenAPI document to stdout
+func main() {
+	gw := gateway.NewGateway(pb.UnimplementedNotificationServiceServer{}, zap.NewNop())
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(gw.OpenAPI()); err != nil {
+		log.Fatalf("Failed to write OpenAPI document: %v", err)
+	}
+}
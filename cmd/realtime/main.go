@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/officiallysidsingh/go-notify/config"
+	"github.com/officiallysidsingh/go-notify/internal/auth"
+	"github.com/officiallysidsingh/go-notify/internal/logging"
+	"github.com/officiallysidsingh/go-notify/internal/realtime"
+	"github.com/officiallysidsingh/go-notify/internal/tenant"
+)
+
+func main() {
+	// Load configuration from the config folder
+	config.LoadConfig("./config")
+
+	// Structured logging, with the same fields as the server and worker
+	logger, err := logging.New(config.AppConfig.Logging.Level)
+	if err != nil {
+		panic(err)
+	}
+	sugar := logger.Sugar()
+	defer func() {
+		if err := logger.Sync(); err != nil {
+			sugar.Errorw("failed to sync logger", "error", err)
+		}
+	}()
+
+	tenants, err := tenant.NewRegistry(config.AppConfig)
+	if err != nil {
+		sugar.Fatalf("Invalid tenant config: %v", err)
+	}
+
+	// Users sign in with JWTs from the same issuer as service tokens
+	var verifier *auth.JWTVerifier
+	if config.AppConfig.Auth.JWKS != "" {
+		keys, err := auth.LoadJWKS(config.AppConfig.Auth.JWKS)
+		if err != nil {
+			sugar.Fatalf("Failed to load JWKS: %v", err)
+		}
+		audience := config.AppConfig.Realtime.Audience
+		if audience == "" {
+			audience = config.AppConfig.Auth.Audience
+		}
+		verifier = auth.NewJWTVerifier(keys, config.AppConfig.Auth.Issuer, audience)
+	} else if config.AppConfig.Realtime.InsecureDevAuth {
+		sugar.Warn("realtime.insecureDevAuth is set; users are taken from the user_id query parameter without authentication")
+	} else {
+		sugar.Fatal("Realtime gateway needs auth.jwks to authenticate users (or realtime.insecureDevAuth for development)")
+	}
+
+	// Cancelled on SIGINT or SIGTERM to shut down gracefully
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Receive events for users connected to this replica
+	hub := realtime.NewHub(config.AppConfig.Redis.Addr, config.AppConfig.Realtime.Retention, logger)
+	go hub.Run(ctx)
+
+	server := &http.Server{
+		Addr:              config.AppConfig.Realtime.Port,
+		Handler:           realtime.NewServer(hub, verifier, tenants, config.AppConfig.Realtime.AllowedOrigins, logger).Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		sugar.Info("Shutting down realtime gateway...")
+
+		// Streams never finish on their own, so don't wait long for them
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			sugar.Errorw("failed to shut down realtime gateway", "error", err)
+		}
+	}()
+
+	sugar.Infof("Realtime gateway running on %s", config.AppConfig.Realtime.Port)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		sugar.Fatalf("Realtime gateway failed: %v", err)
+	}
+}
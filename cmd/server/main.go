@@ -1,33 +1,54 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
 	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/officiallysidsingh/go-notify/config"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"go.uber.org/zap"
 
 	pb "github.com/officiallysidsingh/go-notify/api/generated"
+	"github.com/officiallysidsingh/go-notify/internal/auth"
+	"github.com/officiallysidsingh/go-notify/internal/certs"
+	"github.com/officiallysidsingh/go-notify/internal/dedup"
+	"github.com/officiallysidsingh/go-notify/internal/gateway"
 	grpcserver "github.com/officiallysidsingh/go-notify/internal/grpc"
+	"github.com/officiallysidsingh/go-notify/internal/logging"
 	"github.com/officiallysidsingh/go-notify/internal/producer"
+	"github.com/officiallysidsingh/go-notify/internal/quota"
 	"github.com/officiallysidsingh/go-notify/internal/ratelimiter"
 	"github.com/officiallysidsingh/go-notify/internal/repository"
+	"github.com/officiallysidsingh/go-notify/internal/statuswatch"
+	"github.com/officiallysidsingh/go-notify/internal/tenant"
+	"github.com/officiallysidsingh/go-notify/internal/tracing"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 )
 
 func main() {
+	// Exits non-zero on failure once every deferred cleanup has run
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+
 	// Load configuration from the config folder.
 	config.LoadConfig("./config")
 
-	// Structured logging
-	logger, err := zap.NewProduction()
+	// Structured logging, shared by every component so lines carry the same fields
+	logger, err := logging.New(config.AppConfig.Logging.Level)
 	if err != nil {
 		panic(err)
 	}
@@ -47,9 +68,25 @@ func main() {
 		}
 	}()
 
+	// Cancelled on SIGINT or SIGTERM to shut down gracefully
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Export spans of every request, its queries and published messages
+	shutdownTracing, err := tracing.Setup(context.Background(), config.AppConfig.Tracing, "go-notify-server")
+	if err != nil {
+		sugar.Fatalf("Failed to initialize tracing: %v", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			sugar.Errorw("failed to flush spans", "error", err)
+		}
+	}()
+
 	// Init RabbitMQ Producer
 	producer, err := producer.NewProducer(
 		config.AppConfig.RabbitMQ.URL,
+		logger,
 	)
 	if err != nil {
 		sugar.Fatalf("Failed to initialize RabbitMQ: %v", err)
@@ -57,29 +94,74 @@ func main() {
 	defer producer.Close()
 
 	// Connect to Postgres DB
-	database, err := repository.NewDB(config.AppConfig.Postgres)
+	database, err := repository.NewDB(config.AppConfig.Postgres, logger)
 	if err != nil {
 		sugar.Fatalf("Failed to initialize PostgresDB: %v", err)
 	}
 
 	defer func() {
 		if err := database.Close(); err != nil {
-			log.Printf("error closing database: %v", err)
+			sugar.Errorw("error closing database", "error", err)
 		}
 	}()
 
 	// Convert the Redis window from string to time.Duration
 	redisWindowDuration, err := time.ParseDuration(config.AppConfig.Redis.Window)
 	if err != nil {
-		log.Fatalf("Invalid Redis window duration: %v", err)
+		sugar.Fatalf("Invalid Redis window duration: %v", err)
 	}
 
 	// Connect to Rate Limiter
-	limiter := ratelimiter.NewRateLimiter(
+	limiter, err := ratelimiter.NewRateLimiter(
 		config.AppConfig.Redis.Addr,
 		config.AppConfig.Redis.Limit,
 		redisWindowDuration,
+		config.AppConfig.Redis.Algorithm,
+		logger,
 	)
+	if err != nil {
+		sugar.Fatalf("Failed to initialize rate limiter: %v", err)
+	}
+
+	// Per channel, priority and category limits replacing the per-user limit
+	for _, rule := range config.AppConfig.Redis.Rules {
+		ruleWindow, err := time.ParseDuration(rule.Window)
+		if err != nil {
+			sugar.Fatalf("Invalid rate limit rule window %q: %v", rule.Window, err)
+		}
+		err = limiter.AddRule(ratelimiter.Rule{
+			Channel:  rule.Channel,
+			Priority: rule.Priority,
+			Category: rule.Category,
+			Limit:    rule.Limit,
+			Window:   ruleWindow,
+		})
+		if err != nil {
+			sugar.Fatalf("Failed to add rate limit rule: %v", err)
+		}
+	}
+	if config.AppConfig.Redis.ExemptCritical {
+		limiter.ExemptPriority("5")
+	}
+
+	// Decide how requests are limited while Redis is unavailable
+	if err := limiter.SetFailurePolicy(config.AppConfig.Redis.FailurePolicy); err != nil {
+		sugar.Fatalf("Invalid rate limiter failure policy: %v", err)
+	}
+	healthCheckInterval := 5 * time.Second
+	if config.AppConfig.Redis.HealthCheck != "" {
+		healthCheckInterval, err = time.ParseDuration(config.AppConfig.Redis.HealthCheck)
+		if err != nil {
+			sugar.Fatalf("Invalid Redis health check interval: %v", err)
+		}
+	}
+	limiter.StartHealthCheck(ctx, healthCheckInterval)
+
+	// Deduplication is disabled when no TTL is configured
+	var deduplicator *dedup.Deduplicator
+	if config.AppConfig.Dedup.TTL > 0 {
+		deduplicator = dedup.NewDeduplicator(config.AppConfig.Redis.Addr, config.AppConfig.Dedup.TTL)
+	}
 
 	// Start gRPC Server
 	listener, err := net.Listen("tcp", config.AppConfig.GRPC.Port)
@@ -87,9 +169,104 @@ func main() {
 		sugar.Fatalf("Failed to listen on port %s: %v", config.AppConfig.GRPC.Port, err)
 	}
 
+	// Per-caller daily and monthly quotas
+	quotas := make(quota.Quotas, 0, len(config.AppConfig.Quotas))
+	for _, q := range config.AppConfig.Quotas {
+		quotas = append(quotas, quota.Quota{
+			Caller:  q.Caller,
+			Channel: q.Channel,
+			Daily:   q.Daily,
+			Monthly: q.Monthly,
+		})
+	}
+
+	// Tenants with their own templates and credentials
+	tenants, err := tenant.NewRegistry(config.AppConfig)
+	if err != nil {
+		sugar.Fatalf("Invalid tenant config: %v", err)
+	}
+
+	// Record and trace every call first, then authenticate with API keys or
+	// JWTs before resolving the tenant
+	interceptors := []grpc.UnaryServerInterceptor{
+		grpcserver.MetricsUnaryInterceptor(),
+		grpcserver.TracingUnaryInterceptor(),
+	}
+	streamInterceptors := []grpc.StreamServerInterceptor{
+		grpcserver.MetricsStreamInterceptor(),
+		grpcserver.TracingStreamInterceptor(),
+	}
+	if config.AppConfig.Auth.Enabled {
+		var verifier *auth.JWTVerifier
+		if config.AppConfig.Auth.JWKS != "" {
+			keys, err := auth.LoadJWKS(config.AppConfig.Auth.JWKS)
+			if err != nil {
+				sugar.Fatalf("Failed to load JWKS: %v", err)
+			}
+			verifier = auth.NewJWTVerifier(keys, config.AppConfig.Auth.Issuer, config.AppConfig.Auth.Audience)
+		}
+		authenticator := auth.NewAuthenticator(database, verifier)
+		for _, cert := range config.AppConfig.Auth.ClientCerts {
+			authenticator.AddClientCert(cert.Name, cert.Tenant, auth.ParseScopes(cert.Scopes))
+		}
+		interceptors = append(interceptors, grpcserver.AuthUnaryInterceptor(authenticator, logger))
+		streamInterceptors = append(streamInterceptors, grpcserver.AuthStreamInterceptor(authenticator, logger))
+	} else {
+		sugar.Warn("Authentication is disabled by auth.enabled: false; every RPC, including admin and usage, is open and callers are taken from request metadata")
+	}
+	interceptors = append(interceptors, grpcserver.TenantUnaryInterceptor(tenants))
+	streamInterceptors = append(streamInterceptors, grpcserver.TenantStreamInterceptor(tenants))
+
+	// Push status changes from Postgres to WatchNotificationStatus streams
+	statusHub, err := statuswatch.NewHub(config.AppConfig.Postgres.DataSourceName, logger)
+	if err != nil {
+		sugar.Fatalf("Failed to listen for status changes: %v", err)
+	}
+	go statusHub.Run(ctx)
+
 	// Create gRPC server with integrated notification service
-	server := grpcserver.NewNotificationServer(producer, database, limiter)
-	grpcServer := grpc.NewServer()
+	server := grpcserver.NewNotificationServer(
+		producer,
+		database,
+		limiter,
+		deduplicator,
+		quotas,
+		tenants,
+		statusHub,
+		logger,
+	)
+	serverOptions := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(interceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+	}
+
+	// Serve TLS, and mutual TLS when a client CA is set, reloading rotated certificates
+	var reloader *certs.Reloader
+	if tlsConfig := config.AppConfig.GRPC.TLS; tlsConfig.CertFile != "" {
+		reloader, err = certs.NewReloader(
+			tlsConfig.CertFile,
+			tlsConfig.KeyFile,
+			tlsConfig.ClientCAFile,
+			tlsConfig.ClientAuth,
+			logger,
+		)
+		if err != nil {
+			sugar.Fatalf("Failed to load TLS certificates: %v", err)
+		}
+		reloadInterval := 30 * time.Second
+		if tlsConfig.Reload != "" {
+			reloadInterval, err = time.ParseDuration(tlsConfig.Reload)
+			if err != nil {
+				sugar.Fatalf("Invalid TLS reload interval: %v", err)
+			}
+		}
+		reloader.Watch(ctx, reloadInterval)
+		serverOptions = append(serverOptions, grpc.Creds(credentials.NewTLS(reloader.TLSConfig("h2"))))
+	} else {
+		sugar.Warn("gRPC server is running without TLS")
+	}
+
+	grpcServer := grpc.NewServer(serverOptions...)
 	pb.RegisterNotificationServiceServer(grpcServer, server)
 
 	// Register gRPC health service
@@ -102,8 +279,48 @@ func main() {
 	// Register reflection service for debugging
 	reflection.Register(grpcServer)
 
+	// Serve the HTTP/JSON gateway through the same interceptors
+	var gatewayServer *http.Server
+	if config.AppConfig.Gateway.Port != "" {
+		gatewayServer = &http.Server{
+			Addr:              config.AppConfig.Gateway.Port,
+			Handler:           gateway.NewGateway(server, logger, interceptors...).Handler(),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			sugar.Infof("HTTP gateway running on %s", config.AppConfig.Gateway.Port)
+			var err error
+			if reloader != nil {
+				gatewayServer.TLSConfig = reloader.TLSConfig("h2", "http/1.1")
+				err = gatewayServer.ListenAndServeTLS("", "")
+			} else {
+				err = gatewayServer.ListenAndServe()
+			}
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				sugar.Fatalf("HTTP gateway failed: %v", err)
+			}
+		}()
+	}
+
+	// Stop taking calls on shutdown and let the ones in flight finish
+	go func() {
+		<-ctx.Done()
+		sugar.Info("Shutting down...")
+		healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+
+		if gatewayServer != nil {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := gatewayServer.Shutdown(shutdownCtx); err != nil {
+				sugar.Errorw("failed to shut down HTTP gateway", "error", err)
+			}
+		}
+		grpcServer.GracefulStop()
+	}()
+
 	sugar.Infof("gRPC server running on %s", config.AppConfig.GRPC.Port)
 	if err := grpcServer.Serve(listener); err != nil {
-		sugar.Fatalf("Failed to serve gRPC server: %v", err)
+		sugar.Errorw("Failed to serve gRPC server", "error", err)
+		exitCode = 1
 	}
 }
@@ -1,33 +1,109 @@
 package main
 
 import (
-	"log"
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/officiallysidsingh/go-notify/config"
 	"github.com/officiallysidsingh/go-notify/internal/consumer"
+	"github.com/officiallysidsingh/go-notify/internal/logging"
+	"github.com/officiallysidsingh/go-notify/internal/realtime"
 	"github.com/officiallysidsingh/go-notify/internal/repository"
+	"github.com/officiallysidsingh/go-notify/internal/tenant"
+	"github.com/officiallysidsingh/go-notify/internal/throttle"
+	"github.com/officiallysidsingh/go-notify/internal/tracing"
 )
 
 func main() {
 	// Load configuration from the config folder
 	config.LoadConfig("./config")
 
+	// Structured logging, shared with the consumer and repository
+	logger, err := logging.New(config.AppConfig.Logging.Level)
+	if err != nil {
+		panic(err)
+	}
+	sugar := logger.Sugar()
+	defer func() {
+		if err := logger.Sync(); err != nil {
+			sugar.Errorw("failed to sync logger", "error", err)
+		}
+	}()
+
+	// Serve worker metrics for Prometheus
+	if config.AppConfig.Metrics.WorkerPort != "" {
+		go func() {
+			sugar.Infof("Starting metrics server on %s", config.AppConfig.Metrics.WorkerPort)
+			http.Handle("/metrics", promhttp.Handler())
+			if err := http.ListenAndServe(config.AppConfig.Metrics.WorkerPort, nil); err != nil {
+				sugar.Fatalf("Metrics HTTP server failed: %v", err)
+			}
+		}()
+	}
+
+	// Export spans of processed messages, joined to the request's trace
+	shutdownTracing, err := tracing.Setup(context.Background(), config.AppConfig.Tracing, "go-notify-worker")
+	if err != nil {
+		sugar.Fatalf("Failed to initialize tracing: %v", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			sugar.Errorw("failed to flush spans", "error", err)
+		}
+	}()
+
 	// Connect to PostgreSQL
-	dbConn, err := repository.NewDB(config.AppConfig.Postgres)
+	dbConn, err := repository.NewDB(config.AppConfig.Postgres, logger)
 	if err != nil {
-		log.Fatalf("Failed to initialize PostgresDB: %v", err)
+		sugar.Fatalf("Failed to initialize PostgresDB: %v", err)
 	}
 
 	defer func() {
 		if err := dbConn.Close(); err != nil {
-			log.Printf("error closing dbConn: %v", err)
+			sugar.Errorw("error closing dbConn", "error", err)
 		}
 	}()
 
+	// Pace provider calls across worker replicas through Redis
+	var outbound *throttle.Throttle
+	if len(config.AppConfig.Outbound) > 0 {
+		limits := make(map[string]throttle.Limit, len(config.AppConfig.Outbound))
+		for channel, limit := range config.AppConfig.Outbound {
+			var per time.Duration
+			if limit.Per != "" {
+				per, err = time.ParseDuration(limit.Per)
+				if err != nil {
+					sugar.Fatalf("Invalid outbound period for %s: %v", channel, err)
+				}
+			}
+			limits[channel] = throttle.Limit{
+				Rate:        limit.Rate,
+				Per:         per,
+				Concurrency: limit.Concurrency,
+			}
+		}
+		outbound = throttle.NewThrottle(config.AppConfig.Redis.Addr, limits)
+	}
+
+	// Per-tenant channel credentials
+	tenants, err := tenant.NewRegistry(config.AppConfig)
+	if err != nil {
+		sugar.Fatalf("Invalid tenant config: %v", err)
+	}
+
+	// Realtime notifications reach the gateway replicas through Redis
+	live := realtime.NewPublisher(config.AppConfig.Redis.Addr, config.AppConfig.Realtime.Retention)
+
 	// Create a new consumer with a global worker pool
-	consumer, err := consumer.NewConsumer(config.AppConfig.RabbitMQ.URL, 10, dbConn)
+	consumer, err := consumer.NewConsumer(config.AppConfig.RabbitMQ.URL, 10, dbConn, outbound, tenants, live, logger)
 	if err != nil {
-		log.Fatalf("Failed to initialize consumer: %v", err)
+		sugar.Fatalf("Failed to initialize consumer: %v", err)
 	}
 
 	// Define queues
@@ -36,15 +112,23 @@ func main() {
 		"queue_email",
 		"queue_sms",
 		"queue_push",
+		"queue_inapp",
+		"queue_realtime",
 	}
 
 	// Start the consumer
 	if err := consumer.Start(queues); err != nil {
-		log.Fatalf("Failed to start consumer: %v", err)
+		sugar.Fatalf("Failed to start consumer: %v", err)
 	}
 
-	log.Println("Consumer is up and running, waiting for messages...")
+	sugar.Info("Consumer is up and running, waiting for messages...")
+
+	// Run until asked to stop, then finish the messages in hand so the
+	// deferred cleanup flushes spans and logs
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
 
-	// Block forever
-	select {}
+	sugar.Info("Shutting down consumer...")
+	consumer.Stop()
 }
@@ -10,6 +10,31 @@ import (
 
 type GRPCConfig struct {
 	Port string
+	TLS  TLSConfig
+}
+
+// Server certificate and, for mutual TLS, the CA that signs client certificates
+type TLSConfig struct {
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string
+	ClientAuth   string
+	Reload       string
+}
+
+type GatewayConfig struct {
+	Port string
+}
+
+// Live delivery of "realtime" notifications to browsers over WebSocket and SSE
+type RealtimeConfig struct {
+	Port           string
+	Retention      time.Duration // How long messages for offline users are kept for replay
+	Audience       string        // Expected audience of user tokens, defaults to auth.audience
+	AllowedOrigins []string      // Page origins besides the gateway's own host allowed to open WebSockets
+	// Trusts the user_id and tenant_id query parameters when auth.jwks is
+	// unset. Lets anyone read any user's notifications; development only.
+	InsecureDevAuth bool
 }
 
 type RabbitMQConfig struct {
@@ -26,32 +51,149 @@ type PostgresConfig struct {
 }
 
 type RedisConfig struct {
-	Addr   string
-	Limit  int
-	Window string
+	Addr           string
+	Limit          int
+	Window         string
+	Algorithm      string
+	ExemptCritical bool
+	Rules          []RateLimitRuleConfig
+	FailurePolicy  string
+	HealthCheck    string
+}
+
+// Limits a user's notifications matching a channel, priority and category
+type RateLimitRuleConfig struct {
+	Channel  string `mapstructure:"channel"`
+	Priority string `mapstructure:"priority"`
+	Category string `mapstructure:"category"`
+	Limit    int    `mapstructure:"limit"`
+	Window   string `mapstructure:"window"`
+}
+
+type AuthConfig struct {
+	Enabled     bool
+	JWKS        string
+	Issuer      string
+	Audience    string
+	ClientCerts []ClientCertConfig
+}
+
+// Grants scopes to mTLS clients whose certificate has this URI SAN or common name
+type ClientCertConfig struct {
+	Name   string `mapstructure:"name"`
+	Tenant string `mapstructure:"tenant"`
+	Scopes string `mapstructure:"scopes"`
 }
 
 type MetricsConfig struct {
-	Port string
+	Port       string
+	WorkerPort string
 }
 
 type LoggingConfig struct {
 	Level string
 }
 
+// OpenTelemetry span export
+type TracingConfig struct {
+	Exporter    string  // "otlp", "stdout" or "none"
+	Endpoint    string  // OTLP gRPC collector address
+	Insecure    bool    // Send to the collector without TLS
+	SampleRatio float64 // Share of new traces recorded, traces started upstream follow the caller
+}
+
 type NtfyConfig struct {
 	Topic string
 }
 
+// Paces calls to one provider channel across all workers
+type OutboundLimitConfig struct {
+	Rate        int    `mapstructure:"rate"`
+	Per         string `mapstructure:"per"`
+	Concurrency int    `mapstructure:"concurrency"`
+}
+
+// Caps a caller's notifications on a channel ("*" matches any, 0 is unlimited)
+type QuotaConfig struct {
+	Caller  string `mapstructure:"caller"`
+	Channel string `mapstructure:"channel"`
+	Daily   int    `mapstructure:"daily"`
+	Monthly int    `mapstructure:"monthly"`
+}
+
+// One product served by the deployment, with its own credentials and templates
+type TenantConfig struct {
+	ID             string                    `mapstructure:"id"`
+	CallbackSecret string                    `mapstructure:"callbackSecret"`
+	Ntfy           TenantNtfyConfig          `mapstructure:"ntfy"`
+	SMTP           TenantSMTPConfig          `mapstructure:"smtp"`
+	SMS            TenantSMSConfig           `mapstructure:"sms"`
+	Templates      map[string]TemplateConfig `mapstructure:"templates"`
+}
+
+type TenantNtfyConfig struct {
+	Server string `mapstructure:"server"`
+	Topic  string `mapstructure:"topic"`
+	Token  string `mapstructure:"token"`
+}
+
+type TenantSMTPConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"`
+}
+
+type TenantSMSConfig struct {
+	Provider   string `mapstructure:"provider"`
+	AccountSID string `mapstructure:"accountSid"`
+	AuthToken  string `mapstructure:"authToken"`
+	From       string `mapstructure:"from"`
+}
+
+// Go text/template sources for a notification's title and message
+type TemplateConfig struct {
+	Title   string `mapstructure:"title"`
+	Message string `mapstructure:"message"`
+}
+
+// Signed status events POSTed to a notification's callback_url
+type CallbackConfig struct {
+	Secret      string // Signing secret of the default tenant
+	Timeout     time.Duration
+	MaxAttempts int
+	Backoff     time.Duration // Delay before the first retry, doubled for each later one
+	MaxBackoff  time.Duration
+}
+
+type DedupConfig struct {
+	TTL time.Duration // 0 disables deduplication
+}
+
+type DigestConfig struct {
+	Window time.Duration
+}
+
 // Holds all configuration values.
 type Config struct {
 	GRPC     GRPCConfig
+	Gateway  GatewayConfig
+	Realtime RealtimeConfig
 	RabbitMQ RabbitMQConfig
 	Postgres PostgresConfig
 	Redis    RedisConfig
+	Auth     AuthConfig
 	Metrics  MetricsConfig
 	Logging  LoggingConfig
+	Tracing  TracingConfig
 	Ntfy     NtfyConfig
+	Digest   DigestConfig
+	Dedup    DedupConfig
+	Callback CallbackConfig
+	Outbound map[string]OutboundLimitConfig
+	Quotas   []QuotaConfig
+	Tenants  []TenantConfig
 }
 
 // Global config instance
@@ -67,13 +209,59 @@ func LoadConfig(path string) {
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	viper.AutomaticEnv()
 
+	// Authentication stays on unless a config turns it off explicitly
+	viper.SetDefault("auth.enabled", true)
+
 	if err := viper.ReadInConfig(); err != nil {
 		log.Printf("No config file found: %v", err)
 	}
 
+	var rateLimitRules []RateLimitRuleConfig
+	if err := viper.UnmarshalKey("redis.rules", &rateLimitRules); err != nil {
+		log.Printf("Invalid rate limit rules: %v", err)
+	}
+
+	var outboundLimits map[string]OutboundLimitConfig
+	if err := viper.UnmarshalKey("outbound", &outboundLimits); err != nil {
+		log.Printf("Invalid outbound limits: %v", err)
+	}
+
+	var quotas []QuotaConfig
+	if err := viper.UnmarshalKey("quotas", &quotas); err != nil {
+		log.Printf("Invalid quotas: %v", err)
+	}
+
+	var clientCerts []ClientCertConfig
+	if err := viper.UnmarshalKey("auth.clientCerts", &clientCerts); err != nil {
+		log.Printf("Invalid client certificates: %v", err)
+	}
+
+	var tenants []TenantConfig
+	if err := viper.UnmarshalKey("tenants", &tenants); err != nil {
+		log.Printf("Invalid tenants: %v", err)
+	}
+
 	AppConfig = &Config{
 		GRPC: GRPCConfig{
 			Port: viper.GetString("grpc.port"),
+			TLS: TLSConfig{
+				CertFile:     viper.GetString("grpc.tls.certFile"),
+				KeyFile:      viper.GetString("grpc.tls.keyFile"),
+				ClientCAFile: viper.GetString("grpc.tls.clientCAFile"),
+				ClientAuth:   viper.GetString("grpc.tls.clientAuth"),
+				Reload:       viper.GetString("grpc.tls.reload"),
+			},
+		},
+		Gateway: GatewayConfig{
+			Port: viper.GetString("gateway.port"),
+		},
+		Realtime: RealtimeConfig{
+			Port:      viper.GetString("realtime.port"),
+			Retention: viper.GetDuration("realtime.retention"),
+			Audience:  viper.GetString("realtime.audience"),
+
+			AllowedOrigins:  viper.GetStringSlice("realtime.allowedOrigins"),
+			InsecureDevAuth: viper.GetBool("realtime.insecureDevAuth"),
 		},
 		RabbitMQ: RabbitMQConfig{
 			URL: viper.GetString("rabbitmq.url"),
@@ -87,18 +275,53 @@ func LoadConfig(path string) {
 			ConnTimeout:     viper.GetDuration("postgres.ConnTimeout"),
 		},
 		Redis: RedisConfig{
-			Addr:   viper.GetString("redis.addr"),
-			Limit:  viper.GetInt("redis.limit"),
-			Window: viper.GetString("redis.window"),
+			Addr:           viper.GetString("redis.addr"),
+			Limit:          viper.GetInt("redis.limit"),
+			Window:         viper.GetString("redis.window"),
+			Algorithm:      viper.GetString("redis.algorithm"),
+			ExemptCritical: viper.GetBool("redis.exemptCritical"),
+			Rules:          rateLimitRules,
+			FailurePolicy:  viper.GetString("redis.failurePolicy"),
+			HealthCheck:    viper.GetString("redis.healthCheck"),
+		},
+		Auth: AuthConfig{
+			Enabled:     viper.GetBool("auth.enabled"),
+			JWKS:        viper.GetString("auth.jwks"),
+			Issuer:      viper.GetString("auth.issuer"),
+			Audience:    viper.GetString("auth.audience"),
+			ClientCerts: clientCerts,
 		},
 		Metrics: MetricsConfig{
-			Port: viper.GetString("metrics.port"),
+			Port:       viper.GetString("metrics.port"),
+			WorkerPort: viper.GetString("metrics.workerPort"),
 		},
 		Logging: LoggingConfig{
 			Level: viper.GetString("logging.level"),
 		},
+		Tracing: TracingConfig{
+			Exporter:    viper.GetString("tracing.exporter"),
+			Endpoint:    viper.GetString("tracing.endpoint"),
+			Insecure:    viper.GetBool("tracing.insecure"),
+			SampleRatio: viper.GetFloat64("tracing.sampleRatio"),
+		},
 		Ntfy: NtfyConfig{
 			Topic: viper.GetString("ntfy.topic"),
 		},
+		Digest: DigestConfig{
+			Window: viper.GetDuration("digest.window"),
+		},
+		Dedup: DedupConfig{
+			TTL: viper.GetDuration("dedup.ttl"),
+		},
+		Callback: CallbackConfig{
+			Secret:      viper.GetString("callbacks.secret"),
+			Timeout:     viper.GetDuration("callbacks.timeout"),
+			MaxAttempts: viper.GetInt("callbacks.maxAttempts"),
+			Backoff:     viper.GetDuration("callbacks.backoff"),
+			MaxBackoff:  viper.GetDuration("callbacks.maxBackoff"),
+		},
+		Outbound: outboundLimits,
+		Quotas:   quotas,
+		Tenants:  tenants,
 	}
 }
@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE user_settings (
+    user_id TEXT PRIMARY KEY,
+    locale TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE notifications ADD COLUMN locale TEXT;
+
+-- +goose Down
+ALTER TABLE notifications DROP COLUMN locale;
+
+DROP TABLE user_settings;
@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE user_contacts (
+    id SERIAL PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    address TEXT NOT NULL,
+    label TEXT NOT NULL DEFAULT '',
+    verified BOOLEAN NOT NULL DEFAULT FALSE,
+    verified_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, kind, address)
+);
+
+CREATE INDEX idx_user_contacts_user_kind ON user_contacts (user_id, kind);
+
+-- +goose Down
+DROP TABLE user_contacts;
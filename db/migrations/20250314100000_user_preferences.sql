@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE user_preferences (
+    user_id TEXT NOT NULL,
+    channel TEXT NOT NULL,
+    category TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, channel, category)
+);
+
+ALTER TABLE notifications
+    ADD COLUMN category TEXT,
+    ADD COLUMN status_reason TEXT;
+
+-- +goose Down
+ALTER TABLE notifications
+    DROP COLUMN status_reason,
+    DROP COLUMN category;
+
+DROP TABLE user_preferences;
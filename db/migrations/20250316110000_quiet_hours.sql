@@ -0,0 +1,24 @@
+-- +goose Up
+ALTER TABLE user_settings
+    ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC',
+    ADD COLUMN quiet_start TIME,
+    ADD COLUMN quiet_end TIME;
+
+-- Deferred notifications are republished once deliver_after has passed
+ALTER TABLE notifications
+    ADD COLUMN deliver_after TIMESTAMPTZ,
+    ADD COLUMN payload JSONB;
+
+CREATE INDEX idx_notifications_deferred ON notifications (deliver_after) WHERE status = 'deferred';
+
+-- +goose Down
+DROP INDEX idx_notifications_deferred;
+
+ALTER TABLE notifications
+    DROP COLUMN payload,
+    DROP COLUMN deliver_after;
+
+ALTER TABLE user_settings
+    DROP COLUMN quiet_end,
+    DROP COLUMN quiet_start,
+    DROP COLUMN timezone;
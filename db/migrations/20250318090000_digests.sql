@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE digests (
+    id SERIAL PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    channel TEXT NOT NULL,
+    digest_key TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'open',
+    window_end TIMESTAMPTZ NOT NULL,
+    notification_id INT REFERENCES notifications (id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Only one open digest per user, channel and key accumulates at a time
+CREATE UNIQUE INDEX idx_digests_open ON digests (user_id, channel, digest_key) WHERE status = 'open';
+CREATE INDEX idx_digests_window_end ON digests (window_end) WHERE status = 'open';
+
+ALTER TABLE notifications ADD COLUMN digest_id INT REFERENCES digests (id);
+
+-- +goose Down
+ALTER TABLE notifications DROP COLUMN digest_id;
+
+DROP TABLE digests;
@@ -0,0 +1,24 @@
+-- +goose Up
+ALTER TABLE notifications
+    ADD COLUMN caller TEXT,
+    ADD COLUMN type TEXT;
+
+CREATE INDEX idx_notifications_caller_created_at ON notifications (caller, created_at);
+
+-- Accepted notifications per caller, channel and UTC day, used for quotas and billing
+CREATE TABLE usage_counters (
+    caller TEXT NOT NULL,
+    channel TEXT NOT NULL,
+    day DATE NOT NULL,
+    count INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (caller, channel, day)
+);
+
+-- +goose Down
+DROP TABLE usage_counters;
+
+DROP INDEX idx_notifications_caller_created_at;
+
+ALTER TABLE notifications
+    DROP COLUMN type,
+    DROP COLUMN caller;
@@ -0,0 +1,56 @@
+-- +goose Up
+ALTER TABLE notifications ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
+
+DROP INDEX idx_notifications_caller_created_at;
+CREATE INDEX idx_notifications_tenant_caller_created_at ON notifications (tenant_id, caller, created_at);
+
+ALTER TABLE user_settings ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE user_settings DROP CONSTRAINT user_settings_pkey;
+ALTER TABLE user_settings ADD PRIMARY KEY (tenant_id, user_id);
+
+ALTER TABLE user_contacts ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE user_contacts DROP CONSTRAINT user_contacts_user_id_kind_address_key;
+ALTER TABLE user_contacts ADD CONSTRAINT user_contacts_tenant_user_kind_address_key
+    UNIQUE (tenant_id, user_id, kind, address);
+DROP INDEX idx_user_contacts_user_kind;
+CREATE INDEX idx_user_contacts_tenant_user_kind ON user_contacts (tenant_id, user_id, kind);
+
+ALTER TABLE user_preferences ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE user_preferences DROP CONSTRAINT user_preferences_pkey;
+ALTER TABLE user_preferences ADD PRIMARY KEY (tenant_id, user_id, channel, category);
+
+ALTER TABLE digests ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
+DROP INDEX idx_digests_open;
+CREATE UNIQUE INDEX idx_digests_open ON digests (tenant_id, user_id, channel, digest_key) WHERE status = 'open';
+
+ALTER TABLE usage_counters ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE usage_counters DROP CONSTRAINT usage_counters_pkey;
+ALTER TABLE usage_counters ADD PRIMARY KEY (tenant_id, caller, channel, day);
+
+-- +goose Down
+ALTER TABLE usage_counters DROP CONSTRAINT usage_counters_pkey;
+ALTER TABLE usage_counters DROP COLUMN tenant_id;
+ALTER TABLE usage_counters ADD PRIMARY KEY (caller, channel, day);
+
+DROP INDEX idx_digests_open;
+ALTER TABLE digests DROP COLUMN tenant_id;
+CREATE UNIQUE INDEX idx_digests_open ON digests (user_id, channel, digest_key) WHERE status = 'open';
+
+ALTER TABLE user_preferences DROP CONSTRAINT user_preferences_pkey;
+ALTER TABLE user_preferences DROP COLUMN tenant_id;
+ALTER TABLE user_preferences ADD PRIMARY KEY (user_id, channel, category);
+
+DROP INDEX idx_user_contacts_tenant_user_kind;
+ALTER TABLE user_contacts DROP CONSTRAINT user_contacts_tenant_user_kind_address_key;
+ALTER TABLE user_contacts DROP COLUMN tenant_id;
+ALTER TABLE user_contacts ADD CONSTRAINT user_contacts_user_id_kind_address_key UNIQUE (user_id, kind, address);
+CREATE INDEX idx_user_contacts_user_kind ON user_contacts (user_id, kind);
+
+ALTER TABLE user_settings DROP CONSTRAINT user_settings_pkey;
+ALTER TABLE user_settings DROP COLUMN tenant_id;
+ALTER TABLE user_settings ADD PRIMARY KEY (user_id);
+
+DROP INDEX idx_notifications_tenant_caller_created_at;
+CREATE INDEX idx_notifications_caller_created_at ON notifications (caller, created_at);
+
+ALTER TABLE notifications DROP COLUMN tenant_id;
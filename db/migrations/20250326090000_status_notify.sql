@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE FUNCTION notify_notification_status() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('notification_status', json_build_object(
+        'id', NEW.id,
+        'tenant_id', NEW.tenant_id,
+        'status', NEW.status,
+        'status_reason', COALESCE(NEW.status_reason, '')
+    )::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER notifications_status_changed
+    AFTER UPDATE OF status ON notifications
+    FOR EACH ROW
+    WHEN (OLD.status IS DISTINCT FROM NEW.status)
+    EXECUTE FUNCTION notify_notification_status();
+
+-- +goose Down
+DROP TRIGGER notifications_status_changed ON notifications;
+DROP FUNCTION notify_notification_status();
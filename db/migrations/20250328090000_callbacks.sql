@@ -0,0 +1,32 @@
+-- +goose Up
+CREATE TABLE callbacks (
+    id SERIAL PRIMARY KEY,
+    tenant_id TEXT NOT NULL DEFAULT 'default',
+    notification_id INT NOT NULL REFERENCES notifications (id),
+    url TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_error TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_callbacks_due ON callbacks (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_callbacks_notification ON callbacks (notification_id);
+
+CREATE TABLE callback_attempts (
+    id SERIAL PRIMARY KEY,
+    callback_id INT NOT NULL REFERENCES callbacks (id),
+    attempt INT NOT NULL,
+    status_code INT,
+    error TEXT,
+    duration_ms INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_callback_attempts_callback ON callback_attempts (callback_id);
+
+-- +goose Down
+DROP TABLE callback_attempts;
+DROP TABLE callbacks;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- Keyset pagination for ListNotifications walks (created_at, id) within a tenant,
+-- optionally narrowed to one user or status
+CREATE INDEX idx_notifications_tenant_created_at ON notifications (tenant_id, created_at, id);
+CREATE INDEX idx_notifications_tenant_user_created_at ON notifications (tenant_id, user_id, created_at, id);
+CREATE INDEX idx_notifications_tenant_status_created_at ON notifications (tenant_id, status, created_at, id);
+
+-- +goose Down
+DROP INDEX idx_notifications_tenant_status_created_at;
+DROP INDEX idx_notifications_tenant_user_created_at;
+DROP INDEX idx_notifications_tenant_created_at;
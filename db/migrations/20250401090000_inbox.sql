@@ -0,0 +1,25 @@
+-- +goose Up
+-- In-app notifications, keyed by the notification they were delivered from
+CREATE TABLE inbox_items (
+    notification_id INT PRIMARY KEY REFERENCES notifications (id),
+    tenant_id TEXT NOT NULL DEFAULT 'default',
+    user_id TEXT NOT NULL,
+    title TEXT NOT NULL DEFAULT '',
+    message TEXT NOT NULL,
+    category TEXT,
+    priority TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    read_at TIMESTAMP,
+    archived_at TIMESTAMP
+);
+
+CREATE INDEX idx_inbox_items_user_created_at ON inbox_items (tenant_id, user_id, created_at, notification_id);
+CREATE INDEX idx_inbox_items_unread ON inbox_items (tenant_id, user_id) WHERE read_at IS NULL AND archived_at IS NULL;
+
+-- Set with status 'read' when the user reads the inbox item
+ALTER TABLE notifications ADD COLUMN read_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE notifications DROP COLUMN read_at;
+
+DROP TABLE inbox_items;
@@ -0,0 +1,7 @@
+-- +goose Up
+-- Kept on the notification so a cancellation can be reported to the caller
+-- even when its message never reaches a worker
+ALTER TABLE notifications ADD COLUMN callback_url TEXT;
+
+-- +goose Down
+ALTER TABLE notifications DROP COLUMN callback_url;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- Digests stuck in 'sending' past their lease are claimed again, so a
+-- worker crash or failed send doesn't lose them
+ALTER TABLE digests ADD COLUMN claimed_at TIMESTAMPTZ;
+ALTER TABLE digests ADD COLUMN attempts INT NOT NULL DEFAULT 0;
+
+-- Digests already stuck in 'sending' are retried right away
+UPDATE digests SET claimed_at = window_end WHERE status = 'sending';
+
+CREATE INDEX idx_digests_claimed_at ON digests (claimed_at) WHERE status = 'sending';
+
+-- +goose Down
+DROP INDEX idx_digests_claimed_at;
+
+ALTER TABLE digests DROP COLUMN attempts;
+ALTER TABLE digests DROP COLUMN claimed_at;
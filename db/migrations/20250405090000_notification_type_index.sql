@@ -0,0 +1,6 @@
+-- +goose Up
+-- ListNotifications narrowed to one channel
+CREATE INDEX idx_notifications_tenant_type_created_at ON notifications (tenant_id, type, created_at, id);
+
+-- +goose Down
+DROP INDEX idx_notifications_tenant_type_created_at;
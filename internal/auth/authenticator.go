@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+
+	"github.com/officiallysidsingh/go-notify/internal/repository"
+)
+
+// Request metadata keys carrying credentials
+const (
+	APIKeyMetadataKey        = "x-api-key"
+	AuthorizationMetadataKey = "authorization"
+)
+
+// Authenticator identifies the client behind a request from an API key, a
+// bearer JWT or a known mTLS client certificate
+type Authenticator struct {
+	db          *repository.DB
+	jwt         *JWTVerifier
+	clientCerts map[string]*Principal
+}
+
+// Creates an authenticator. A nil verifier disables JWT authentication.
+func NewAuthenticator(db *repository.DB, jwt *JWTVerifier) *Authenticator {
+	return &Authenticator{
+		db:          db,
+		jwt:         jwt,
+		clientCerts: make(map[string]*Principal),
+	}
+}
+
+// Authenticates clients presenting a verified certificate with the given
+// URI SAN or common name, when they send no other credentials
+func (a *Authenticator) AddClientCert(name, tenantID string, scopes []string) {
+	a.clientCerts[name] = &Principal{Subject: name, Tenant: tenantID, Scopes: scopes}
+}
+
+// Returns the principal for the credentials in the request metadata or the
+// connection's client certificate
+func (a *Authenticator) Authenticate(ctx context.Context) (*Principal, error) {
+	identity, _ := PeerIdentity(ctx)
+
+	p, err := a.authenticate(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+	p.Identity = identity
+	return p, nil
+}
+
+func (a *Authenticator) authenticate(ctx context.Context, identity *Identity) (*Principal, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if values := md.Get(APIKeyMetadataKey); len(values) > 0 {
+		key, err := a.db.GetAPIKeyByHash(ctx, HashAPIKey(values[0]))
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUnauthenticated
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &Principal{Subject: key.Name, Tenant: key.TenantID, Scopes: key.Scopes}, nil
+	}
+
+	if values := md.Get(AuthorizationMetadataKey); len(values) > 0 && a.jwt != nil {
+		token, ok := strings.CutPrefix(values[0], "Bearer ")
+		if !ok {
+			return nil, ErrUnauthenticated
+		}
+		p, err := a.jwt.Verify(strings.TrimSpace(token), time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+		}
+		return p, nil
+	}
+
+	if identity != nil {
+		if known, ok := a.clientCerts[identity.Name()]; ok {
+			p := *known
+			return &p, nil
+		}
+	}
+
+	return nil, ErrUnauthenticated
+}
@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"context"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// Identity is the client certificate verified during the mTLS handshake
+type Identity struct {
+	CommonName   string
+	DNSNames     []string
+	URIs         []string // e.g. SPIFFE IDs
+	SerialNumber string
+}
+
+// Returns the name clients are matched by: the first URI SAN, or the common name
+func (i *Identity) Name() string {
+	if len(i.URIs) > 0 {
+		return i.URIs[0]
+	}
+	return i.CommonName
+}
+
+// Returns the identity of the client certificate verified for the request's
+// connection. Unverified certificates are never returned.
+func PeerIdentity(ctx context.Context) (*Identity, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return nil, false
+	}
+
+	cert := info.State.VerifiedChains[0][0]
+	identity := &Identity{
+		CommonName:   cert.Subject.CommonName,
+		DNSNames:     cert.DNSNames,
+		SerialNumber: cert.SerialNumber.String(),
+	}
+	for _, uri := range cert.URIs {
+		identity.URIs = append(identity.URIs, uri.String())
+	}
+	return identity, true
+}
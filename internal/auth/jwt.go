@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Tolerated clock difference when checking exp and nbf
+const clockSkew = time.Minute
+
+// Claims read from a verified token
+type claims struct {
+	Subject   string          `json:"sub"`
+	Issuer    string          `json:"iss"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *int64          `json:"exp"`
+	NotBefore *int64          `json:"nbf"`
+	Scope     string          `json:"scope"`
+	Scp       []string        `json:"scp"`
+	Tenant    string          `json:"tenant"`
+}
+
+// JWTVerifier checks token signatures against a JWKS and validates claims
+type JWTVerifier struct {
+	keys     map[string]crypto.PublicKey
+	issuer   string
+	audience string
+}
+
+// Creates a JWT verifier. Empty issuer or audience are not checked.
+func NewJWTVerifier(keys map[string]crypto.PublicKey, issuer, audience string) *JWTVerifier {
+	return &JWTVerifier{
+		keys:     keys,
+		issuer:   issuer,
+		audience: audience,
+	}
+}
+
+// Verifies a compact JWT and returns its principal
+func (v *JWTVerifier) Verify(token string, now time.Time) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid token header: %w", err)
+	}
+
+	key, ok := v.keys[header.Kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", header.Kid)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid token signature: %w", err)
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var c claims
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return nil, fmt.Errorf("invalid token claims: %w", err)
+	}
+	if err := v.validate(&c, now); err != nil {
+		return nil, err
+	}
+
+	scopes := c.Scp
+	if c.Scope != "" {
+		scopes = ParseScopes(c.Scope)
+	}
+	return &Principal{Subject: c.Subject, Tenant: c.Tenant, Scopes: scopes}, nil
+}
+
+// Checks expiry, issuer and audience
+func (v *JWTVerifier) validate(c *claims, now time.Time) error {
+	if c.ExpiresAt == nil {
+		return errors.New("token has no expiry")
+	}
+	if now.After(time.Unix(*c.ExpiresAt, 0).Add(clockSkew)) {
+		return errors.New("token expired")
+	}
+	if c.NotBefore != nil && now.Add(clockSkew).Before(time.Unix(*c.NotBefore, 0)) {
+		return errors.New("token not valid yet")
+	}
+	if v.issuer != "" && c.Issuer != v.issuer {
+		return errors.New("unexpected token issuer")
+	}
+	if v.audience != "" && !hasAudience(c.Audience, v.audience) {
+		return errors.New("unexpected token audience")
+	}
+	return nil
+}
+
+// The aud claim may be a single string or a list
+func hasAudience(raw json.RawMessage, audience string) bool {
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return single == audience
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return false
+	}
+	for _, a := range list {
+		if a == audience {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks the signature over the signing input for the header's algorithm.
+// The algorithm must match the key type so "none" and HMAC are never accepted.
+func verifySignature(alg string, key crypto.PublicKey, signingInput string, signature []byte) error {
+	if len(alg) != 5 {
+		return fmt.Errorf("unsupported token algorithm %q", alg)
+	}
+
+	var (
+		h        hash.Hash
+		hashFunc crypto.Hash
+	)
+	switch alg[2:] {
+	case "256":
+		h, hashFunc = sha256.New(), crypto.SHA256
+	case "384":
+		h, hashFunc = sha512.New384(), crypto.SHA384
+	case "512":
+		h, hashFunc = sha512.New(), crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported token algorithm %q", alg)
+	}
+	h.Write([]byte(signingInput))
+	digest := h.Sum(nil)
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		switch alg[:2] {
+		case "RS":
+			return rsa.VerifyPKCS1v15(k, hashFunc, digest, signature)
+		case "PS":
+			return rsa.VerifyPSS(k, hashFunc, digest, signature, nil)
+		}
+	case *ecdsa.PublicKey:
+		if alg[:2] == "ES" && len(signature)%2 == 0 {
+			half := len(signature) / 2
+			r := new(big.Int).SetBytes(signature[:half])
+			s := new(big.Int).SetBytes(signature[half:])
+			if ecdsa.Verify(k, digest, r, s) {
+				return nil
+			}
+			return errors.New("invalid token signature")
+		}
+	}
+	return fmt.Errorf("token algorithm %q does not match signing key", alg)
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
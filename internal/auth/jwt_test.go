@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testNow = time.Unix(1_700_000_000, 0)
+
+func encodeSegment(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Builds a compact JWT signed with an RSA or P-256 key
+func signToken(t *testing.T, key crypto.Signer, alg, kid string, claims map[string]interface{}) string {
+	t.Helper()
+	signingInput := encodeSegment(t, map[string]string{"alg": alg, "kid": kid}) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var signature []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		require.NoError(t, err)
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestJWTVerifierVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	verifier := NewJWTVerifier(map[string]crypto.PublicKey{
+		"rsa": rsaKey.Public(),
+		"ec":  ecKey.Public(),
+	}, "https://issuer.example", "go-notify")
+
+	valid := func(overrides map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{
+			"sub":    "client-1",
+			"iss":    "https://issuer.example",
+			"aud":    "go-notify",
+			"exp":    testNow.Add(time.Hour).Unix(),
+			"scope":  "notifications:send notifications:read",
+			"tenant": "acme",
+		}
+		for k, v := range overrides {
+			if v == nil {
+				delete(c, k)
+			} else {
+				c[k] = v
+			}
+		}
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr string
+	}{
+		{
+			name:  "rsa",
+			token: signToken(t, rsaKey, "RS256", "rsa", valid(nil)),
+		},
+		{
+			name:  "ecdsa",
+			token: signToken(t, ecKey, "ES256", "ec", valid(nil)),
+		},
+		{
+			name:  "audience list",
+			token: signToken(t, rsaKey, "RS256", "rsa", valid(map[string]interface{}{"aud": []string{"other", "go-notify"}})),
+		},
+		{
+			name:  "expired within clock skew",
+			token: signToken(t, rsaKey, "RS256", "rsa", valid(map[string]interface{}{"exp": testNow.Add(-30 * time.Second).Unix()})),
+		},
+		{
+			name:    "expired",
+			token:   signToken(t, rsaKey, "RS256", "rsa", valid(map[string]interface{}{"exp": testNow.Add(-2 * time.Minute).Unix()})),
+			wantErr: "token expired",
+		},
+		{
+			name:    "no expiry",
+			token:   signToken(t, rsaKey, "RS256", "rsa", valid(map[string]interface{}{"exp": nil})),
+			wantErr: "token has no expiry",
+		},
+		{
+			name:    "not valid yet",
+			token:   signToken(t, rsaKey, "RS256", "rsa", valid(map[string]interface{}{"nbf": testNow.Add(2 * time.Minute).Unix()})),
+			wantErr: "token not valid yet",
+		},
+		{
+			name:    "wrong issuer",
+			token:   signToken(t, rsaKey, "RS256", "rsa", valid(map[string]interface{}{"iss": "https://evil.example"})),
+			wantErr: "unexpected token issuer",
+		},
+		{
+			name:    "wrong audience",
+			token:   signToken(t, rsaKey, "RS256", "rsa", valid(map[string]interface{}{"aud": "other"})),
+			wantErr: "unexpected token audience",
+		},
+		{
+			name:    "audience list without ours",
+			token:   signToken(t, rsaKey, "RS256", "rsa", valid(map[string]interface{}{"aud": []string{"a", "b"}})),
+			wantErr: "unexpected token audience",
+		},
+		{
+			name:    "signed by another key",
+			token:   signToken(t, otherKey, "RS256", "rsa", valid(nil)),
+			wantErr: "verification error",
+		},
+		{
+			name:    "unknown key id",
+			token:   signToken(t, rsaKey, "RS256", "missing", valid(nil)),
+			wantErr: `unknown signing key "missing"`,
+		},
+		{
+			name:    "algorithm does not match key",
+			token:   signToken(t, rsaKey, "ES256", "rsa", valid(nil)),
+			wantErr: "does not match signing key",
+		},
+		{
+			name:    "alg none",
+			token:   encodeSegment(t, map[string]string{"alg": "none", "kid": "rsa"}) + "." + encodeSegment(t, valid(nil)) + ".",
+			wantErr: `unsupported token algorithm "none"`,
+		},
+		{
+			name:    "malformed",
+			token:   "not-a-token",
+			wantErr: "malformed token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := verifier.Verify(tt.token, testNow)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				assert.Nil(t, principal)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "client-1", principal.Subject)
+			assert.Equal(t, "acme", principal.Tenant)
+			assert.Equal(t, []string{ScopeSend, ScopeRead}, principal.Scopes)
+		})
+	}
+}
+
+func TestJWTVerifierTamperedClaims(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	verifier := NewJWTVerifier(map[string]crypto.PublicKey{"rsa": key.Public()}, "", "")
+
+	token := signToken(t, key, "RS256", "rsa", map[string]interface{}{
+		"sub":    "client-1",
+		"exp":    testNow.Add(time.Hour).Unix(),
+		"tenant": "acme",
+	})
+	parts := strings.Split(token, ".")
+	parts[1] = encodeSegment(t, map[string]interface{}{
+		"sub":   "client-1",
+		"exp":   testNow.Add(time.Hour).Unix(),
+		"scope": ScopeAdmin,
+	})
+
+	_, err = verifier.Verify(strings.Join(parts, "."), testNow)
+	assert.Error(t, err)
+}
+
+func TestPrincipalCanAccessTenant(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal Principal
+		tenantID  string
+		want      bool
+	}{
+		{"own tenant", Principal{Tenant: "acme", Scopes: []string{ScopeSend}}, "acme", true},
+		{"other tenant", Principal{Tenant: "acme", Scopes: []string{ScopeSend}}, "globex", false},
+		{"bound admin stays in its tenant", Principal{Tenant: "acme", Scopes: []string{ScopeAdmin}}, "globex", false},
+		{"unbound without admin", Principal{Scopes: []string{ScopeSend}}, "acme", false},
+		{"unbound admin", Principal{Scopes: []string{ScopeAdmin}}, "acme", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.principal.CanAccessTenant(tt.tenantID))
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package callback
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Header carrying the event signature, as "t=<unix seconds>,v1=<hex HMAC>"
+const SignatureHeader = "X-Notify-Signature"
+
+// Event is the body POSTed to a callback URL when a notification reaches a
+// final status
+type Event struct {
+	NotificationID int64     `json:"notification_id"`
+	TenantID       string    `json:"tenant_id"`
+	UserID         string    `json:"user_id"`
+	Status         string    `json:"status"`
+	StatusReason   string    `json:"status_reason,omitempty"`
+	OccurredAt     time.Time `json:"occurred_at"`
+}
+
+// Returned when a callback would reach a loopback, private or link-local address
+var ErrForbiddenAddress = errors.New("callback address is not publicly routable")
+
+// Checks that a callback URL is an absolute https URL. Hosts given as IP
+// addresses must be public; names are checked when the sender dials them.
+func ValidateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid callback_url: %w", err)
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return errors.New("callback_url must be an absolute https URL")
+	}
+	if ip, err := netip.ParseAddr(u.Hostname()); err == nil && !isPublic(ip) {
+		return fmt.Errorf("invalid callback_url: %w", ErrForbiddenAddress)
+	}
+	return nil
+}
+
+// Reports whether the address may be called back. Internal services,
+// cloud metadata endpoints and the like must not be reachable through
+// tenant supplied URLs.
+func isPublic(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsValid() &&
+		!ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified()
+}
+
+// Rejects connections to non-public addresses. It runs on the resolved
+// address right before connecting, so a name that later resolves (or
+// rebinds) to an internal address is still refused.
+func checkDialAddress(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid callback address %q: %w", address, err)
+	}
+	if !isPublic(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, addrPort.Addr())
+	}
+	return nil
+}
+
+// Signs "<timestamp>.<body>" with HMAC-SHA256. Receivers recompute it with
+// the shared secret and reject stale timestamps to prevent replays.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Sender POSTs signed events
+type Sender struct {
+	client *http.Client
+}
+
+// Creates a new Sender
+func NewSender(timeout time.Duration) *Sender {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: checkDialAddress,
+	}
+	return &Sender{
+		client: &http.Client{
+			Timeout: timeout,
+			// No proxy, as it would dial the target on our behalf unchecked
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				TLSHandshakeTimeout: timeout,
+				MaxIdleConnsPerHost: 2,
+				IdleConnTimeout:     90 * time.Second,
+				ForceAttemptHTTP2:   true,
+			},
+			// Redirects could send the signed event somewhere else
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// POSTs a signed body and returns the response status code.
+// Any non-2xx response is returned as an error.
+func (s *Sender) Post(ctx context.Context, callbackURL, secret string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-notify-callback/1")
+	req.Header.Set(SignatureHeader, Sign(secret, time.Now(), body))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		// Drain so the connection can be reused
+		_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+		_ = res.Body.Close()
+	}()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("callback returned status %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
+
+// Returns the delay before retry number attempt (1-based), doubling from
+// base up to max
+func Backoff(attempt int, base, max time.Duration) time.Duration {
+	d := base
+	for i := 1; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
@@ -0,0 +1,104 @@
+package callback
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSign(t *testing.T) {
+	timestamp := time.Unix(1_700_000_000, 0)
+	body := []byte(`{"notification_id":1,"status":"sent"}`)
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1700000000." + string(body)))
+	want := "t=1700000000,v1=" + hex.EncodeToString(mac.Sum(nil))
+
+	assert.Equal(t, want, Sign("secret", timestamp, body))
+	assert.NotEqual(t, want, Sign("other", timestamp, body))
+	assert.NotEqual(t, want, Sign("secret", timestamp.Add(time.Second), body))
+	assert.NotEqual(t, want, Sign("secret", timestamp, []byte(`{}`)))
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 0, want: time.Second},
+		{attempt: 1, want: time.Second},
+		{attempt: 2, want: 2 * time.Second},
+		{attempt: 3, want: 4 * time.Second},
+		{attempt: 5, want: 16 * time.Second},
+		{attempt: 6, want: 30 * time.Second},
+		{attempt: 100, want: 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Backoff(tt.attempt, time.Second, 30*time.Second), "attempt %d", tt.attempt)
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		url       string
+		wantErr   bool
+		forbidden bool
+	}{
+		{name: "public host", url: "https://hooks.example.com/notify"},
+		{name: "public address", url: "https://93.184.216.34/notify"},
+		{name: "http", url: "http://hooks.example.com/notify", wantErr: true},
+		{name: "relative", url: "/notify", wantErr: true},
+		{name: "no host", url: "https:///notify", wantErr: true},
+		{name: "loopback", url: "https://127.0.0.1/notify", wantErr: true, forbidden: true},
+		{name: "private", url: "https://10.0.0.5/notify", wantErr: true, forbidden: true},
+		{name: "metadata endpoint", url: "https://169.254.169.254/latest", wantErr: true, forbidden: true},
+		{name: "ipv6 loopback", url: "https://[::1]/notify", wantErr: true, forbidden: true},
+		{name: "mapped ipv4 loopback", url: "https://[::ffff:127.0.0.1]/notify", wantErr: true, forbidden: true},
+		{name: "unspecified", url: "https://0.0.0.0/notify", wantErr: true, forbidden: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateURL(tt.url)
+			if !tt.wantErr {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			if tt.forbidden {
+				assert.ErrorIs(t, err, ErrForbiddenAddress)
+			}
+		})
+	}
+}
+
+func TestCheckDialAddress(t *testing.T) {
+	assert.NoError(t, checkDialAddress("tcp4", "93.184.216.34:443", nil))
+	assert.ErrorIs(t, checkDialAddress("tcp4", "127.0.0.1:443", nil), ErrForbiddenAddress)
+	assert.ErrorIs(t, checkDialAddress("tcp4", "192.168.1.1:443", nil), ErrForbiddenAddress)
+	assert.ErrorIs(t, checkDialAddress("tcp6", "[fe80::1]:443", nil), ErrForbiddenAddress)
+	assert.Error(t, checkDialAddress("tcp4", "not-an-address", nil))
+}
+
+// Names aren't checked up front, so the dial must refuse where they resolve to
+func TestSenderRefusesInternalAddresses(t *testing.T) {
+	called := false
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	_, err := NewSender(time.Second).Post(context.Background(), server.URL, "secret", []byte(`{}`))
+	assert.ErrorIs(t, err, ErrForbiddenAddress)
+	assert.False(t, called)
+}
@@ -0,0 +1,145 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/officiallysidsingh/go-notify/config"
+	"github.com/officiallysidsingh/go-notify/internal/callback"
+	"github.com/officiallysidsingh/go-notify/internal/logging"
+	"github.com/officiallysidsingh/go-notify/internal/repository"
+)
+
+// Used when the callbacks section leaves them unset
+const (
+	defaultCallbackTimeout     = 5 * time.Second
+	defaultCallbackMaxAttempts = 8
+	defaultCallbackBackoff     = 30 * time.Second
+	defaultCallbackMaxBackoff  = time.Hour
+
+	// How often pending callbacks are retried
+	callbackInterval = 5 * time.Second
+	// Max callbacks claimed per tick, small enough to be sent well within
+	// their lease one after another
+	callbackBatchSize = 10
+	// Time to claim callbacks and record an attempt, on top of the POST itself
+	callbackDBTimeout = 5 * time.Second
+)
+
+// Queues a status event for the notification's callback_url, if it has one
+func (c *Consumer) queueCallback(ctx context.Context, notifMsg *NotificationMessage, status, reason string) {
+	if notifMsg.CallbackURL == "" {
+		return
+	}
+
+	payload, err := json.Marshal(callback.Event{
+		NotificationID: notifMsg.NotificationID,
+		TenantID:       notifMsg.TenantID,
+		UserID:         notifMsg.UserID,
+		Status:         status,
+		StatusReason:   reason,
+		OccurredAt:     time.Now().UTC(),
+	})
+	if err != nil {
+		c.logger(ctx).Error("Failed to encode callback", zap.Error(err))
+		return
+	}
+
+	if err := c.dbConn.InsertCallback(ctx, notifMsg.NotificationID, notifMsg.CallbackURL, payload); err != nil {
+		c.logger(ctx).Error("Failed to queue callback", zap.Error(err))
+		return
+	}
+
+	// Deliver it now rather than on the next tick
+	select {
+	case c.callbackWake <- struct{}{}:
+	default:
+	}
+}
+
+// Delivers queued callbacks until the consumer stops
+func (c *Consumer) runCallbacks() {
+	ticker := time.NewTicker(callbackInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			c.deliverCallbacks()
+		case <-c.callbackWake:
+			c.deliverCallbacks()
+		}
+	}
+}
+
+// Claims due callbacks and POSTs each one, scheduling a retry on failure
+func (c *Consumer) deliverCallbacks() {
+	ctx, cancel := context.WithTimeout(context.Background(), callbackDBTimeout)
+	defer cancel()
+
+	due, err := c.dbConn.ClaimDueCallbacks(ctx, callbackBatchSize)
+	if err != nil {
+		c.logger(ctx).Error("Failed to claim due callbacks", zap.Error(err))
+		return
+	}
+
+	for _, cb := range due {
+		c.deliverCallback(cb)
+	}
+}
+
+// POSTs one callback and records the attempt, with its own deadline so a
+// slow receiver can't use up the time of the ones after it
+func (c *Consumer) deliverCallback(cb repository.Callback) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.callbackTimeout+callbackDBTimeout)
+	defer cancel()
+
+	cfg := config.AppConfig.Callback
+	maxAttempts := cfg.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultCallbackMaxAttempts
+	}
+	backoff := cfg.Backoff
+	if backoff <= 0 {
+		backoff = defaultCallbackBackoff
+	}
+	maxBackoff := cfg.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultCallbackMaxBackoff
+	}
+
+	logger := c.logger(ctx).With(zap.Int64("callback_id", cb.ID), logging.NotificationID(cb.NotificationID))
+
+	var attempt repository.CallbackAttempt
+	start := time.Now()
+
+	// The secret is looked up on every attempt so rotations apply to retries
+	t, err := c.tenants.Get(cb.TenantID)
+	if err == nil {
+		attempt.StatusCode, err = c.callbacks.Post(ctx, cb.URL, t.CallbackSecret, cb.Payload)
+	}
+	attempt.Duration = time.Since(start)
+
+	status := "delivered"
+	nextAttempt := time.Now()
+	if err != nil {
+		attempt.Error = err.Error()
+		if cb.Attempts+1 >= maxAttempts {
+			logger.Warn("Giving up callback", zap.Int("attempts", cb.Attempts+1), zap.Error(err))
+			status = "failed"
+		} else {
+			logger.Warn("Callback failed, retrying", zap.Error(err))
+			status = "pending"
+			nextAttempt = nextAttempt.Add(callback.Backoff(cb.Attempts+1, backoff, maxBackoff))
+		}
+	}
+
+	if err := c.dbConn.RecordCallbackAttempt(ctx, cb, attempt, status, nextAttempt); err != nil {
+		logger.Error("Failed to record callback attempt", zap.Error(err))
+	}
+}
@@ -2,15 +2,29 @@ package consumer
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
 	"sync"
 	"time"
 
 	"github.com/officiallysidsingh/go-notify/config"
+	"github.com/officiallysidsingh/go-notify/internal/callback"
+	"github.com/officiallysidsingh/go-notify/internal/logging"
+	"github.com/officiallysidsingh/go-notify/internal/realtime"
+	"github.com/officiallysidsingh/go-notify/internal/recipients"
 	"github.com/officiallysidsingh/go-notify/internal/repository"
 	"github.com/officiallysidsingh/go-notify/internal/service"
+	"github.com/officiallysidsingh/go-notify/internal/tenant"
+	"github.com/officiallysidsingh/go-notify/internal/throttle"
+	"github.com/officiallysidsingh/go-notify/internal/tracing"
 	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
 )
 
 // Payload from RabbitMQ
@@ -21,6 +35,21 @@ type NotificationMessage struct {
 	Priority       string `json:"priority"`
 	Message        string `json:"message"`
 	Type           string `json:"type"`
+	TenantID       string `json:"tenant_id,omitempty"`
+	Category       string `json:"category,omitempty"`
+	DigestKey      string `json:"digest_key,omitempty"`
+	CallbackURL    string `json:"callback_url,omitempty"`
+
+	Localized map[string]LocalizedContent `json:"localized,omitempty"`
+}
+
+// Span attribute naming the notification a message carries
+const notificationIDKey = attribute.Key("notification.id")
+
+// Per-locale variant of the title and message
+type LocalizedContent struct {
+	Title   string `json:"title"`
+	Message string `json:"message"`
 }
 
 // Message wraps a RabbitMQ delivery with its queue name
@@ -34,13 +63,34 @@ type Consumer struct {
 	conn       *amqp.Connection
 	ch         *amqp.Channel
 	dbConn     *repository.DB
+	recipients *recipients.Resolver
+	throttle   *throttle.Throttle
+	tenants    *tenant.Registry
+	realtime   *realtime.Publisher
 	msgChannel chan Message
 	workers    int
 	wg         sync.WaitGroup
+	forwarders sync.WaitGroup // Moving deliveries into msgChannel
+	queues     []string       // Consumed queues, also their consumer tags
+	done       chan struct{}
+	log        *zap.Logger
+
+	// Status callbacks to the calling service
+	callbacks       *callback.Sender
+	callbackTimeout time.Duration
+	callbackWake    chan struct{}
 }
 
-// Create a new Consumer instance
-func NewConsumer(amqpURL string, workers int, db *repository.DB) (*Consumer, error) {
+// Create a new Consumer instance, a nil outbound throttle disables pacing
+func NewConsumer(
+	amqpURL string,
+	workers int,
+	db *repository.DB,
+	outbound *throttle.Throttle,
+	tenants *tenant.Registry,
+	live *realtime.Publisher,
+	logger *zap.Logger,
+) (*Consumer, error) {
 	// Connect to RabbitMQ
 	conn, err := amqp.Dial(amqpURL)
 	if err != nil {
@@ -50,17 +100,34 @@ func NewConsumer(amqpURL string, workers int, db *repository.DB) (*Consumer, err
 	ch, err := conn.Channel()
 	if err != nil {
 		if err := conn.Close(); err != nil {
-			log.Printf("error closing connection: %v", err)
+			logger.Error("error closing connection", zap.Error(err))
 		}
 		return nil, err
 	}
 
+	watchAMQP(conn, ch)
+
+	callbackTimeout := config.AppConfig.Callback.Timeout
+	if callbackTimeout <= 0 {
+		callbackTimeout = defaultCallbackTimeout
+	}
+
 	return &Consumer{
 		conn:       conn,
 		ch:         ch,
 		dbConn:     db,
+		recipients: recipients.NewResolver(db),
+		throttle:   outbound,
+		tenants:    tenants,
+		realtime:   live,
 		workers:    workers,
 		msgChannel: make(chan Message, 100),
+		done:       make(chan struct{}),
+		log:        logger,
+
+		callbacks:       callback.NewSender(callbackTimeout),
+		callbackTimeout: callbackTimeout,
+		callbackWake:    make(chan struct{}, 1),
 	}, nil
 }
 
@@ -96,10 +163,10 @@ func (c *Consumer) Start(queues []string) error {
 			return err
 		}
 
-		// Consume messages
+		// Consume messages, tagged with the queue name so Stop can cancel them
 		msgs, err := c.ch.Consume(
 			queueName,
-			"",
+			queueName,
 			false,
 			false,
 			false,
@@ -110,10 +177,15 @@ func (c *Consumer) Start(queues []string) error {
 			return err
 		}
 
+		c.queues = append(c.queues, queueName)
+
 		// Push messages from each queue into the global msgChannel
+		c.forwarders.Add(1)
 		go func(q string, deliveries <-chan amqp.Delivery) {
+			defer c.forwarders.Done()
 			for d := range deliveries {
 				c.msgChannel <- Message{QueueName: q, Delivery: d}
+				msgChannelDepth.Set(float64(len(c.msgChannel)))
 			}
 		}(queueName, msgs)
 	}
@@ -124,6 +196,20 @@ func (c *Consumer) Start(queues []string) error {
 		go c.worker()
 	}
 
+	// Republish deferred notifications when they are due
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		c.runScheduler()
+	}()
+
+	// POST status events to callback URLs, retrying failed ones
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		c.runCallbacks()
+	}()
+
 	return nil
 }
 
@@ -131,7 +217,11 @@ func (c *Consumer) Start(queues []string) error {
 func (c *Consumer) worker() {
 	defer c.wg.Done()
 	for msg := range c.msgChannel {
+		msgChannelDepth.Set(float64(len(c.msgChannel)))
+		messagesInFlight.Inc()
+		trackOutcome(&msg)
 		c.processMessage(msg)
+		messagesInFlight.Dec()
 	}
 }
 
@@ -140,12 +230,27 @@ func (c *Consumer) processMessage(msg Message) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	// Continue the trace of the request that published the message
+	ctx = tracing.ExtractAMQP(ctx, msg.Delivery.Headers)
+	ctx, span := tracing.Tracer().Start(
+		ctx,
+		msg.QueueName+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemRabbitmq,
+			semconv.MessagingOperationTypeDeliver,
+			semconv.MessagingDestinationName(msg.QueueName),
+		),
+	)
+	defer span.End()
+	ctx = logging.NewContext(ctx, logging.WithTrace(ctx, c.log).With(logging.Channel(channelLabel(msg.QueueName))))
+
 	// In DLQ, simply log the message for manual intervention
 	if msg.QueueName == "dead_letter_queue" {
-		log.Printf("Received DLQ message: %s", string(msg.Delivery.Body))
+		c.logger(ctx).Warn("Received DLQ message", zap.ByteString("body", msg.Delivery.Body))
 		// Acknowledge the message to remove it from the DLQ
 		if err := msg.Delivery.Ack(false); err != nil {
-			log.Printf("Error acknowledging DLQ message: %v", err)
+			c.logger(ctx).Error("Error acknowledging DLQ message", zap.Error(err))
 		}
 		return
 	}
@@ -154,89 +259,305 @@ func (c *Consumer) processMessage(msg Message) {
 	var notifMsg NotificationMessage
 	err := json.Unmarshal(msg.Delivery.Body, &notifMsg)
 	if err != nil {
-		log.Printf("Error unmarshaling message from queue %s: %v", msg.QueueName, err)
+		c.logger(ctx).Error("Error unmarshaling message", zap.Error(err))
 		// Reject the message without requeueing
 		if nackErr := msg.Delivery.Nack(false, false); nackErr != nil {
-			log.Printf("Error sending Nack for queue %s: %v", msg.QueueName, nackErr)
+			c.logger(ctx).Error("Error sending Nack", zap.Error(nackErr))
+		}
+		return
+	}
+
+	// Every later log line names the notification
+	ctx = logging.NewContext(ctx, c.logger(ctx).With(
+		logging.NotificationID(notifMsg.NotificationID),
+		logging.UserID(notifMsg.UserID),
+	))
+	c.logger(ctx).Info("Processing notification")
+	span.SetAttributes(notificationIDKey.Int64(notifMsg.NotificationID))
+
+	// Scope lookups and provider credentials to the notification's tenant
+	t, err := c.tenants.Get(notifMsg.TenantID)
+	if err != nil {
+		c.logger(ctx).Warn("Dropping notification", zap.Error(err))
+
+		// Recorded under the notification's own tenant, whose secret must sign the callback
+		ctx := tenant.NewContext(ctx, notifMsg.TenantID)
+		if updateErr := c.dbConn.UpdateNotificationStatusWithReason(ctx, notifMsg.NotificationID, "failed", err.Error()); updateErr != nil {
+			c.logger(ctx).Error("Failed updating status", zap.Error(updateErr))
+		} else {
+			c.queueCallback(ctx, &notifMsg, "failed", err.Error())
+		}
+		if err := msg.Delivery.Nack(false, false); err != nil {
+			c.logger(ctx).Error("Error sending Nack", zap.Error(err))
+		}
+		return
+	}
+	ctx = tenant.NewContext(ctx, t.ID)
+
+	// The caller may have cancelled it while it was queued
+	cancelled, err := c.dbConn.IsNotificationCancelled(ctx, notifMsg.NotificationID)
+	if err != nil {
+		c.logger(ctx).Error("Failed to check notification", zap.Error(err))
+		if err := msg.Delivery.Nack(false, true); err != nil {
+			c.logger(ctx).Error("Error sending Nack", zap.Error(err))
+		}
+		return
+	}
+	if cancelled {
+		c.logger(ctx).Info("Skipping cancelled notification")
+		setOutcome(msg, outcomeCancelled)
+		if err := msg.Delivery.Ack(false); err != nil {
+			c.logger(ctx).Error("Error sending Ack", zap.Error(err))
+		}
+		return
+	}
+
+	// Drop notifications the user has opted out of
+	reason, err := c.suppressionReason(ctx, &notifMsg)
+	if err != nil {
+		c.logger(ctx).Error("Failed to check preferences", zap.Error(err))
+		if err := msg.Delivery.Nack(false, true); err != nil {
+			c.logger(ctx).Error("Error sending Nack", zap.Error(err))
 		}
 		return
 	}
+	if reason != "" {
+		c.suppress(ctx, msg, &notifMsg, reason)
+		return
+	}
 
-	log.Printf("Processing notification %d from %s", notifMsg.NotificationID, msg.QueueName)
+	// Swap in the best localized variant before handing off to a sender
+	c.localize(ctx, &notifMsg)
+
+	// Tagged notifications are held and sent as one summary per window
+	if notifMsg.DigestKey != "" {
+		c.collectIntoDigest(ctx, msg, &notifMsg)
+		return
+	}
+
+	// Hold non-urgent push and SMS until the user's quiet hours end
+	until, inQuietHours, err := c.quietHoursEnd(ctx, &notifMsg)
+	if err != nil {
+		c.logger(ctx).Error("Failed to check quiet hours", zap.Error(err))
+		if err := msg.Delivery.Nack(false, true); err != nil {
+			c.logger(ctx).Error("Error sending Nack", zap.Error(err))
+		}
+		return
+	}
+	if inQuietHours {
+		reason := fmt.Sprintf("quiet hours until %s", until.Format(time.RFC3339))
+		c.deferUntil(ctx, msg, notifMsg.NotificationID, until, reason)
+		return
+	}
+
+	// Look up where this user receives notifications on this channel
+	destinations, err := c.resolveDestinations(ctx, t, msg.QueueName, notifMsg.UserID)
+	if err != nil {
+		c.logger(ctx).Error("Failed to resolve destination", zap.Error(err))
+
+		requeue := true
+		if errors.Is(err, recipients.ErrNoContact) {
+			// Retrying won't help until the user registers a contact
+			requeue = false
+			if updateErr := c.dbConn.UpdateNotificationStatus(ctx, notifMsg.NotificationID, "failed"); updateErr != nil {
+				c.logger(ctx).Error("Failed updating status", zap.Error(updateErr))
+			} else {
+				c.queueCallback(ctx, &notifMsg, "failed", err.Error())
+			}
+		}
+
+		if err := msg.Delivery.Nack(false, requeue); err != nil {
+			c.logger(ctx).Error("Error sending Nack", zap.Error(err))
+		}
+		return
+	}
+
+	// Pace calls to the provider across all worker replicas
+	release, ok := c.acquireOutbound(ctx, msg, &notifMsg)
+	if !ok {
+		return
+	}
+	defer release()
 
 	// Process the message based on which queue it came from
+	sendCtx, sendSpan := tracing.Tracer().Start(
+		ctx,
+		"send "+channelLabel(msg.QueueName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(notificationIDKey.Int64(notifMsg.NotificationID)),
+	)
+	providerStart := time.Now()
 	switch msg.QueueName {
 	case "queue_email":
 		// TODO: Implement email notification
-		println("Make SendEmailNotification Service")
+		c.logger(ctx).Warn("Email sending is not implemented", zap.Int("destinations", len(destinations)))
 	case "queue_sms":
 		// TODO: Implement SMS notification
-		println("Make SendSMSNotification Service")
+		c.logger(ctx).Warn("SMS sending is not implemented", zap.Int("destinations", len(destinations)))
 	case "queue_push":
-		err = service.SendPushNotification(
-			config.AppConfig.Ntfy.Topic,
-			notifMsg.Title,
-			notifMsg.Priority,
-			notifMsg.Message,
-		)
+		err = c.sendPush(ctx, t, &notifMsg, destinations)
+	case "queue_inapp":
+		// Stored in the user's inbox instead of calling a provider, and
+		// marked sent along with it
+		err = c.dbConn.InsertInboxItem(sendCtx, repository.InboxItem{
+			NotificationID: notifMsg.NotificationID,
+			UserID:         notifMsg.UserID,
+			Title:          notifMsg.Title,
+			Message:        notifMsg.Message,
+			Category:       sql.NullString{String: notifMsg.Category, Valid: notifMsg.Category != ""},
+			Priority:       sql.NullString{String: notifMsg.Priority, Valid: notifMsg.Priority != ""},
+		})
+	case "queue_realtime":
+		// Fanned out to the user's browser sessions by the realtime gateway
+		_, err = c.realtime.Publish(sendCtx, t.ID, notifMsg.UserID, realtime.Event{
+			ID:        notifMsg.NotificationID,
+			Title:     notifMsg.Title,
+			Message:   notifMsg.Message,
+			Priority:  notifMsg.Priority,
+			Category:  notifMsg.Category,
+			CreatedAt: time.Now().UTC(),
+		})
 	default:
-		log.Printf("Unknown queue: %s", msg.QueueName)
+		c.logger(ctx).Error("Unknown queue", zap.String("queue", msg.QueueName))
+	}
+	// The inbox insert found the notification cancelled; not a provider failure
+	cancelled = errors.Is(err, repository.ErrCancelled)
+	if !cancelled {
+		observeProvider(channelLabel(msg.QueueName), providerStart, err)
 	}
+	if err != nil && !cancelled {
+		sendSpan.RecordError(err)
+		sendSpan.SetStatus(otelcodes.Error, "send failed")
+		span.SetStatus(otelcodes.Error, "send failed")
+	}
+	sendSpan.End()
 
-	if err != nil {
-		log.Printf(
-			"Failed to process notification %d from queue %s: %v",
-			notifMsg.NotificationID,
-			msg.QueueName,
-			err,
-		)
+	// The provider throttled us, so retry once it accepts calls again
+	var rateLimited *service.RateLimitedError
+	if errors.As(err, &rateLimited) {
+		c.logger(ctx).Warn("Provider rate limited", zap.Error(err))
+		c.pauseOutbound(ctx, msg, &notifMsg, rateLimited.RetryAfter)
+		return
+	}
+
+	if err != nil && !cancelled {
+		c.logger(ctx).Error("Failed to process notification", zap.Error(err))
 
-		// For updating the status to "failed"
-		err = c.dbConn.UpdateNotificationStatus(ctx, notifMsg.NotificationID, "failed")
+		// Not "failed", which is final; the requeued message is tried again
+		setOutcome(msg, outcomeFailed)
+		err = c.dbConn.UpdateNotificationStatusWithReason(ctx, notifMsg.NotificationID, "retrying", err.Error())
 		if err != nil {
-			log.Printf("Failed updating status: %v", err)
+			c.logger(ctx).Error("Failed updating status", zap.Error(err))
 		}
 
 		// Requeue the message after a short delay
 		time.Sleep(2 * time.Second)
 		err = msg.Delivery.Nack(false, true)
 		if err != nil {
-			log.Printf("Error sending Nack for queue %s: %v", msg.QueueName, err)
+			c.logger(ctx).Error("Error sending Nack", zap.Error(err))
 		}
 		return
 	}
 
-	// Update DB status to "sent" on successful processing
-	if err := c.dbConn.UpdateNotificationStatus(ctx, notifMsg.NotificationID, "sent"); err != nil {
-		log.Printf(
-			"Failed to update notification status for notification %d: %v",
-			notifMsg.NotificationID,
-			err,
-		)
+	// Update DB status to "sent" on successful processing. In-app items
+	// already were, so a read that came in meanwhile isn't overwritten.
+	if msg.QueueName != "queue_inapp" {
+		err = c.dbConn.UpdateNotificationStatus(ctx, notifMsg.NotificationID, "sent")
+	}
+	if errors.Is(err, repository.ErrCancelled) {
+		// Cancelled while it was being sent; the cancellation stands
+		c.logger(ctx).Info("Notification cancelled during delivery")
+		setOutcome(msg, outcomeCancelled)
+		if err := msg.Delivery.Ack(false); err != nil {
+			c.logger(ctx).Error("Error sending Ack", zap.Error(err))
+		}
+		return
+	}
+	if err != nil {
+		c.logger(ctx).Error("Failed to update notification status", zap.Error(err))
 		if err := msg.Delivery.Nack(false, true); err != nil {
-			log.Printf("Error sending Nack for queue %s: %v", msg.QueueName, err)
+			c.logger(ctx).Error("Error sending Nack", zap.Error(err))
 		}
 		return
 	}
+	c.queueCallback(ctx, &notifMsg, "sent", "")
+	setOutcome(msg, outcomeSent)
 
 	// Acknowledge successful processing
 	if err := msg.Delivery.Ack(false); err != nil {
-		log.Printf("Error sending Ack for queue %s: %v", msg.QueueName, err)
+		c.logger(ctx).Error("Error sending Ack", zap.Error(err))
 	} else {
-		log.Printf("Notification %d processed and sent from queue %s.", notifMsg.NotificationID, msg.QueueName)
+		c.logger(ctx).Info("Notification sent")
 	}
 }
 
-// Stop gracefully shuts down the consumer.
+// Publishes the notification to each of the user's ntfy topics. It counts as
+// sent once any topic has it, since requeueing would push it again to the
+// topics that did; the error is returned only when every topic failed.
+func (c *Consumer) sendPush(ctx context.Context, t *tenant.Tenant, notifMsg *NotificationMessage, topics []string) error {
+	var (
+		delivered int
+		lastErr   error
+	)
+	for i, topic := range topics {
+		err := service.SendPushNotification(
+			ctx,
+			t.Ntfy.Server,
+			t.Ntfy.Token,
+			topic,
+			notifMsg.Title,
+			notifMsg.Priority,
+			notifMsg.Message,
+		)
+		if err != nil {
+			c.logger(ctx).Warn("Failed to push to topic", zap.Int("topic_index", i), zap.Error(err))
+			lastErr = err
+
+			// The server throttles the remaining topics just the same
+			var rateLimited *service.RateLimitedError
+			if errors.As(err, &rateLimited) {
+				break
+			}
+			continue
+		}
+		delivered++
+	}
+
+	if delivered == 0 {
+		return lastErr
+	}
+	if lastErr != nil {
+		c.logger(ctx).Warn("Push reached only some topics", zap.Int("delivered", delivered), zap.Int("topics", len(topics)))
+	}
+	return nil
+}
+
+// Returns the logger of the message being handled in ctx, or the consumer's
+func (c *Consumer) logger(ctx context.Context) *zap.Logger {
+	return logging.FromContext(ctx, c.log)
+}
+
+// Stop gracefully shuts down the consumer. Deliveries stop first, then the
+// messages already received are processed before the connection closes.
 func (c *Consumer) Stop() {
+	close(c.done)
+
+	// Closes the delivery channels, so nothing sends on msgChannel after it closes
+	for _, queue := range c.queues {
+		if err := c.ch.Cancel(queue, false); err != nil {
+			c.log.Error("error cancelling consumer", zap.String("queue", queue), zap.Error(err))
+		}
+	}
+	c.forwarders.Wait()
+
 	close(c.msgChannel)
 	c.wg.Wait()
 
 	if err := c.ch.Close(); err != nil {
-		log.Printf("error closing channel: %v", err)
+		c.log.Error("error closing channel", zap.Error(err))
 	}
 
 	if err := c.conn.Close(); err != nil {
-		log.Printf("error closing consumer connection: %v", err)
+		c.log.Error("error closing consumer connection", zap.Error(err))
 	}
 }
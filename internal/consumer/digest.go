@@ -0,0 +1,207 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/officiallysidsingh/go-notify/config"
+	"github.com/officiallysidsingh/go-notify/internal/digest"
+	"github.com/officiallysidsingh/go-notify/internal/logging"
+	"github.com/officiallysidsingh/go-notify/internal/repository"
+	"github.com/officiallysidsingh/go-notify/internal/tenant"
+)
+
+const (
+	// Used when digest.window is not configured
+	defaultDigestWindow = 15 * time.Minute
+	// Sends of one digest tried before it is marked failed
+	maxDigestAttempts = 5
+)
+
+// Collects the notification into its user's open digest and removes it from the queue
+func (c *Consumer) collectIntoDigest(ctx context.Context, msg Message, notifMsg *NotificationMessage) {
+	window := config.AppConfig.Digest.Window
+	if window <= 0 {
+		window = defaultDigestWindow
+	}
+
+	// Store the localized payload so the summary matches what would have been sent
+	payload, err := json.Marshal(notifMsg)
+	if err != nil {
+		payload = msg.Delivery.Body
+	}
+
+	digestID, err := c.dbConn.AddToDigest(
+		ctx,
+		notifMsg.NotificationID,
+		notifMsg.UserID,
+		notifMsg.Type,
+		notifMsg.DigestKey,
+		time.Now().Add(window),
+		payload,
+	)
+	if err != nil {
+		c.logger(ctx).Error("Failed to add notification to digest", zap.Error(err))
+		if err := msg.Delivery.Nack(false, true); err != nil {
+			c.logger(ctx).Error("Error sending Nack", zap.Error(err))
+		}
+		return
+	}
+
+	c.logger(ctx).Info("Notification collected into digest", zap.Int64("digest_id", digestID))
+	setOutcome(msg, outcomeDigested)
+	if err := msg.Delivery.Ack(false); err != nil {
+		c.logger(ctx).Error("Error sending Ack", zap.Error(err))
+	}
+}
+
+// Sends one summary notification for every digest whose window has closed
+func (c *Consumer) flushDigests() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	due, err := c.dbConn.ClaimDueDigests(ctx, schedulerBatchSize)
+	if err != nil {
+		c.logger(ctx).Error("Failed to claim due digests", zap.Error(err))
+		return
+	}
+
+	for _, d := range due {
+		summaryID, err := c.sendDigest(ctx, d)
+		status := "sent"
+		if err != nil {
+			if d.Attempts < maxDigestAttempts {
+				// Left claimed, so it is retried once the lease runs out
+				c.logger(ctx).Warn("Failed to send digest, retrying", zap.Int64("digest_id", d.ID), zap.Int("attempts", d.Attempts), zap.Error(err))
+				continue
+			}
+			c.logger(ctx).Error("Giving up digest", zap.Int64("digest_id", d.ID), zap.Int("attempts", d.Attempts), zap.Error(err))
+			status = "failed"
+
+			// The summary was left pending for the retries
+			if summaryID != 0 {
+				tctx := tenant.NewContext(ctx, d.TenantID)
+				if err := c.dbConn.UpdateNotificationStatusWithReason(tctx, summaryID, "failed", "failed to publish digest"); err != nil {
+					c.logger(ctx).Error("Failed updating status", logging.NotificationID(summaryID), zap.Error(err))
+				}
+			}
+		}
+
+		if err := c.dbConn.CompleteDigest(ctx, d.ID, summaryID, status); err != nil {
+			c.logger(ctx).Error("Failed to complete digest", zap.Int64("digest_id", d.ID), zap.Error(err))
+		}
+	}
+}
+
+// Renders a digest into a summary notification and publishes it for delivery.
+// Retries reuse the summary created by the first attempt, and skip publishing
+// once the worker has picked it up, so the user gets the digest once.
+func (c *Consumer) sendDigest(ctx context.Context, d repository.Digest) (int64, error) {
+	// The summary belongs to the digest's tenant
+	ctx = tenant.NewContext(ctx, d.TenantID)
+
+	summaryID := d.NotificationID.Int64
+	if d.NotificationID.Valid {
+		status, err := c.dbConn.GetNotificationStatus(ctx, summaryID)
+		if err != nil {
+			return summaryID, err
+		}
+		if status.Status != "pending" {
+			// Published by an earlier attempt that failed to complete the digest
+			return summaryID, nil
+		}
+	}
+
+	items, err := c.dbConn.GetDigestItems(ctx, d.ID)
+	if err != nil {
+		return summaryID, err
+	}
+	summary := NotificationMessage{
+		UserID:   d.UserID,
+		Type:     d.Channel,
+		TenantID: d.TenantID,
+	}
+	rendered := make([]digest.Item, 0, len(items))
+	collected := make([]NotificationMessage, 0, len(items))
+	for i, item := range items {
+		var notifMsg NotificationMessage
+		if err := json.Unmarshal(item.Payload, &notifMsg); err != nil {
+			c.logger(ctx).Warn("Skipping digest item with invalid payload", zap.Int64("digest_id", d.ID), zap.Int64("item_id", item.ID), zap.Error(err))
+			continue
+		}
+		rendered = append(rendered, digest.Item{Title: notifMsg.Title, Message: notifMsg.Message})
+		collected = append(collected, notifMsg)
+
+		// The summary is as urgent as its most urgent item
+		if higherPriority(notifMsg.Priority, summary.Priority) {
+			summary.Priority = notifMsg.Priority
+		}
+
+		// Keep the category only if every item shares it
+		if i == 0 {
+			summary.Category = notifMsg.Category
+		} else if summary.Category != notifMsg.Category {
+			summary.Category = ""
+		}
+	}
+	if len(rendered) == 0 {
+		return summaryID, nil
+	}
+
+	summary.Title, summary.Message = digest.Render(d.DigestKey, rendered)
+
+	summary.NotificationID = summaryID
+	if !d.NotificationID.Valid {
+		summary.NotificationID, err = c.dbConn.InsertDigestSummary(ctx, d.ID, repository.NewNotification{
+			UserID:   summary.UserID,
+			Type:     summary.Type,
+			Category: summary.Category,
+			Message:  summary.Message,
+			Status:   "pending",
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return summary.NotificationID, err
+	}
+
+	// Left pending on failure so the next attempt publishes it
+	if err := c.publish(ctx, summary.Type, data); err != nil {
+		return summary.NotificationID, err
+	}
+
+	c.logger(ctx).Info(
+		"Digest sent",
+		zap.Int64("digest_id", d.ID),
+		logging.NotificationID(summary.NotificationID),
+		logging.UserID(summary.UserID),
+		logging.Channel(summary.Type),
+		zap.Int("items", len(rendered)),
+	)
+
+	// The items end here, delivered as part of the summary
+	reason := fmt.Sprintf("sent in digest notification %d", summary.NotificationID)
+	for i := range collected {
+		c.queueCallback(ctx, &collected[i], "digested", reason)
+	}
+	return summary.NotificationID, nil
+}
+
+// Reports whether priority a outranks b ("1" lowest to "5" highest)
+func higherPriority(a, b string) bool {
+	pa, errA := strconv.Atoi(a)
+	if errA != nil {
+		return false
+	}
+	pb, errB := strconv.Atoi(b)
+	return errB != nil || pa > pb
+}
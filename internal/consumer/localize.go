@@ -0,0 +1,39 @@
+package consumer
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/officiallysidsingh/go-notify/internal/locale"
+)
+
+// Replaces the title and message with the variant matching the user's locale
+// (e.g. pt-BR -> pt -> default) and records the chosen locale
+func (c *Consumer) localize(ctx context.Context, notifMsg *NotificationMessage) {
+	if len(notifMsg.Localized) == 0 {
+		return
+	}
+
+	userLocale, err := c.dbConn.GetUserLocale(ctx, notifMsg.UserID)
+	if err != nil {
+		// Fall back to the default content rather than failing the delivery
+		c.logger(ctx).Error("Failed to get locale", zap.Error(err))
+	}
+
+	chosen := locale.Default
+	if key, ok := locale.Match(userLocale, notifMsg.Localized); ok {
+		variant := notifMsg.Localized[key]
+		if variant.Title != "" {
+			notifMsg.Title = variant.Title
+		}
+		if variant.Message != "" {
+			notifMsg.Message = variant.Message
+		}
+		chosen = locale.Normalize(key)
+	}
+
+	if err := c.dbConn.UpdateNotificationLocale(ctx, notifMsg.NotificationID, chosen); err != nil {
+		c.logger(ctx).Error("Failed to record locale", zap.Error(err))
+	}
+}
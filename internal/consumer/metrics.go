@@ -0,0 +1,177 @@
+package consumer
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/streadway/amqp"
+)
+
+// How a message left the queue, as the outcome label of worker_messages_total
+const (
+	outcomeSent         = "sent"
+	outcomeFailed       = "failed" // The provider call failed and the message was requeued
+	outcomeRequeued     = "requeued"
+	outcomeDeadLettered = "dead_lettered"
+	outcomeSuppressed   = "suppressed"
+	outcomeDeferred     = "deferred"
+	outcomeDigested     = "digested"
+	outcomeCancelled    = "cancelled"
+	outcomeAcked        = "acked" // Acked for any other reason
+)
+
+var (
+	messagesProcessed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "worker_messages_total",
+			Help: "Total number of messages processed by channel and outcome",
+		},
+		[]string{"channel", "outcome"},
+	)
+
+	processingDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "worker_processing_duration_seconds",
+			Help:    "Time from taking a message off msgChannel until it is acked or nacked",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"channel"},
+	)
+
+	providerDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "worker_provider_duration_seconds",
+			Help:    "Time spent delivering to the channel's provider",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"channel", "result"},
+	)
+
+	messagesInFlight = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "worker_messages_in_flight",
+			Help: "Messages currently being processed",
+		},
+	)
+
+	msgChannelDepth = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "worker_msg_channel_depth",
+			Help: "Messages buffered in msgChannel waiting for a worker",
+		},
+	)
+
+	// 1 while open, 0 once closed
+	amqpUp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "worker_amqp_up",
+			Help: "Whether the worker's AMQP connection and channel are open",
+		},
+		[]string{"resource"},
+	)
+)
+
+// Init prometheus metrics
+func init() {
+	prometheus.MustRegister(
+		messagesProcessed,
+		processingDuration,
+		providerDuration,
+		messagesInFlight,
+		msgChannelDepth,
+		amqpUp,
+	)
+}
+
+// Channel label of a queue ("queue_push" is "push")
+func channelLabel(queueName string) string {
+	return strings.TrimPrefix(queueName, "queue_")
+}
+
+// Tracks the AMQP connection and channel state until they close
+func watchAMQP(conn *amqp.Connection, ch *amqp.Channel) {
+	amqpUp.WithLabelValues("connection").Set(1)
+	amqpUp.WithLabelValues("channel").Set(1)
+
+	connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
+	chClosed := ch.NotifyClose(make(chan *amqp.Error, 1))
+	go func() {
+		<-connClosed
+		amqpUp.WithLabelValues("connection").Set(0)
+	}()
+	go func() {
+		<-chClosed
+		amqpUp.WithLabelValues("channel").Set(0)
+	}()
+}
+
+// outcomeAcknowledger counts each message by how it is acked or nacked
+type outcomeAcknowledger struct {
+	amqp.Acknowledger
+	channel string
+	start   time.Time
+	outcome string // Set by the step that settles the message
+}
+
+// Wraps the delivery's acknowledger so that settling it records the outcome
+func trackOutcome(msg *Message) {
+	msg.Delivery.Acknowledger = &outcomeAcknowledger{
+		Acknowledger: msg.Delivery.Acknowledger,
+		channel:      channelLabel(msg.QueueName),
+		start:        time.Now(),
+	}
+}
+
+// Names the outcome counted when the message is acked, or "failed" for a
+// requeue after a failed provider call
+func setOutcome(msg Message, outcome string) {
+	if a, ok := msg.Delivery.Acknowledger.(*outcomeAcknowledger); ok {
+		a.outcome = outcome
+	}
+}
+
+func (a *outcomeAcknowledger) Ack(tag uint64, multiple bool) error {
+	outcome := a.outcome
+	if outcome == "" || outcome == outcomeFailed {
+		outcome = outcomeAcked
+	}
+	a.record(outcome)
+	return a.Acknowledger.Ack(tag, multiple)
+}
+
+func (a *outcomeAcknowledger) Nack(tag uint64, multiple, requeue bool) error {
+	a.record(a.nackOutcome(requeue))
+	return a.Acknowledger.Nack(tag, multiple, requeue)
+}
+
+func (a *outcomeAcknowledger) Reject(tag uint64, requeue bool) error {
+	a.record(a.nackOutcome(requeue))
+	return a.Acknowledger.Reject(tag, requeue)
+}
+
+func (a *outcomeAcknowledger) nackOutcome(requeue bool) string {
+	switch {
+	case !requeue:
+		// Main queues dead-letter rejected messages
+		return outcomeDeadLettered
+	case a.outcome == outcomeFailed:
+		return outcomeFailed
+	default:
+		return outcomeRequeued
+	}
+}
+
+func (a *outcomeAcknowledger) record(outcome string) {
+	messagesProcessed.WithLabelValues(a.channel, outcome).Inc()
+	processingDuration.WithLabelValues(a.channel).Observe(time.Since(a.start).Seconds())
+}
+
+// Observes one provider call
+func observeProvider(channel string, start time.Time, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	providerDuration.WithLabelValues(channel, result).Observe(time.Since(start).Seconds())
+}
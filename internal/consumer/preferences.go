@@ -0,0 +1,45 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// Returns why the notification must not be sent, or "" if the user accepts it
+func (c *Consumer) suppressionReason(ctx context.Context, notifMsg *NotificationMessage) (string, error) {
+	category := notifMsg.Category
+	if category == "" {
+		category = "default"
+	}
+
+	enabled, err := c.dbConn.IsChannelEnabled(ctx, notifMsg.UserID, notifMsg.Type, category)
+	if err != nil {
+		return "", err
+	}
+	if enabled {
+		return "", nil
+	}
+
+	return fmt.Sprintf("user opted out of %s notifications via %s", category, notifMsg.Type), nil
+}
+
+// Marks the notification as suppressed and removes it from the queue
+func (c *Consumer) suppress(ctx context.Context, msg Message, notifMsg *NotificationMessage, reason string) {
+	c.logger(ctx).Info("Suppressing notification", zap.String("reason", reason))
+
+	if err := c.dbConn.UpdateNotificationStatusWithReason(ctx, notifMsg.NotificationID, "suppressed", reason); err != nil {
+		c.logger(ctx).Error("Failed to update notification status", zap.Error(err))
+		if err := msg.Delivery.Nack(false, true); err != nil {
+			c.logger(ctx).Error("Error sending Nack", zap.Error(err))
+		}
+		return
+	}
+	c.queueCallback(ctx, notifMsg, "suppressed", reason)
+	setOutcome(msg, outcomeSuppressed)
+
+	if err := msg.Delivery.Ack(false); err != nil {
+		c.logger(ctx).Error("Error sending Ack", zap.Error(err))
+	}
+}
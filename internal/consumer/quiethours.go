@@ -0,0 +1,62 @@
+package consumer
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/officiallysidsingh/go-notify/internal/quiethours"
+)
+
+// Channels that respect quiet hours
+var quietHoursChannels = map[string]bool{
+	"push": true,
+	"sms":  true,
+}
+
+// Priority that always bypasses quiet hours
+const urgentPriority = "5"
+
+// Returns when the user's quiet hours end if the notification must wait for it
+func (c *Consumer) quietHoursEnd(ctx context.Context, notifMsg *NotificationMessage) (time.Time, bool, error) {
+	if !quietHoursChannels[notifMsg.Type] || notifMsg.Priority == urgentPriority {
+		return time.Time{}, false, nil
+	}
+
+	settings, err := c.dbConn.GetQuietHours(ctx, notifMsg.UserID)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if settings.Start == "" || settings.End == "" {
+		return time.Time{}, false, nil
+	}
+
+	window, err := quiethours.Parse(settings.Start, settings.End, settings.Timezone)
+	if err != nil {
+		// A bad stored setting shouldn't block delivery
+		c.logger(ctx).Warn("Ignoring invalid quiet hours", zap.Error(err))
+		return time.Time{}, false, nil
+	}
+
+	until, inside := window.Until(time.Now())
+	return until, inside, nil
+}
+
+// Marks the notification as deferred until the given time and removes it from the queue
+func (c *Consumer) deferUntil(ctx context.Context, msg Message, notificationID int64, until time.Time, reason string) {
+	c.logger(ctx).Info("Deferring notification", zap.String("reason", reason))
+
+	if err := c.dbConn.DeferNotification(ctx, notificationID, until, msg.Delivery.Body, reason); err != nil {
+		c.logger(ctx).Error("Failed to defer notification", zap.Error(err))
+		if err := msg.Delivery.Nack(false, true); err != nil {
+			c.logger(ctx).Error("Error sending Nack", zap.Error(err))
+		}
+		return
+	}
+	setOutcome(msg, outcomeDeferred)
+
+	if err := msg.Delivery.Ack(false); err != nil {
+		c.logger(ctx).Error("Error sending Ack", zap.Error(err))
+	}
+}
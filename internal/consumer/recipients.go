@@ -0,0 +1,32 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+
+	"github.com/officiallysidsingh/go-notify/internal/recipients"
+	"github.com/officiallysidsingh/go-notify/internal/tenant"
+)
+
+// Returns the addresses the queue's sender should deliver to for a tenant's user
+func (c *Consumer) resolveDestinations(
+	ctx context.Context,
+	t *tenant.Tenant,
+	queueName, userID string,
+) ([]string, error) {
+	kind := recipients.KindForQueue(queueName)
+	if kind == "" {
+		return nil, nil
+	}
+
+	addresses, err := c.recipients.Resolve(ctx, userID, kind)
+
+	// Users without a registered topic still get the tenant's shared ntfy topic
+	if errors.Is(err, recipients.ErrNoContact) &&
+		kind == recipients.KindPushTopic &&
+		t.Ntfy.Topic != "" {
+		return []string{t.Ntfy.Topic}, nil
+	}
+
+	return addresses, err
+}
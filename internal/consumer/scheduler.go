@@ -0,0 +1,97 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/streadway/amqp"
+	"go.uber.org/zap"
+
+	"github.com/officiallysidsingh/go-notify/internal/logging"
+	"github.com/officiallysidsingh/go-notify/internal/tenant"
+	"github.com/officiallysidsingh/go-notify/internal/tracing"
+)
+
+const (
+	// How often deferred notifications and digests are checked
+	schedulerInterval = 15 * time.Second
+	// Max notifications republished per tick
+	schedulerBatchSize = 100
+)
+
+// Republishes deferred notifications and flushes digests once they are due
+func (c *Consumer) runScheduler() {
+	ticker := time.NewTicker(schedulerInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			c.republishDue()
+			c.flushDigests()
+		}
+	}
+}
+
+// Claims due notifications and puts them back on their queue
+func (c *Consumer) republishDue() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	due, err := c.dbConn.ClaimDueNotifications(ctx, schedulerBatchSize)
+	if err != nil {
+		c.logger(ctx).Error("Failed to claim due notifications", zap.Error(err))
+		return
+	}
+
+	for _, n := range due {
+		ctx := tenant.NewContext(ctx, n.TenantID)
+
+		var notifMsg NotificationMessage
+		if err := json.Unmarshal(n.Payload, &notifMsg); err != nil {
+			c.logger(ctx).Error("Dropping deferred notification with invalid payload", logging.NotificationID(n.ID), zap.Error(err))
+			if err := c.dbConn.UpdateNotificationStatusWithReason(ctx, n.ID, "failed", "invalid deferred payload"); err != nil {
+				c.logger(ctx).Error("Failed updating status", logging.NotificationID(n.ID), zap.Error(err))
+			}
+			continue
+		}
+
+		if err := c.publish(ctx, notifMsg.Type, n.Payload); err != nil {
+			// Still deferred, so it is claimed again once the lease runs out
+			c.logger(ctx).Error("Failed to republish deferred notification", logging.NotificationID(n.ID), zap.Error(err))
+			continue
+		}
+
+		// If this fails it stays deferred and is published again after the
+		// lease, which may deliver it twice
+		if err := c.dbConn.MarkRepublished(ctx, n); err != nil {
+			c.logger(ctx).Error("Failed to mark notification republished", logging.NotificationID(n.ID), zap.Error(err))
+		}
+
+		c.logger(ctx).Info(
+			"Republished deferred notification",
+			logging.NotificationID(n.ID),
+			logging.UserID(notifMsg.UserID),
+			logging.Channel(notifMsg.Type),
+		)
+	}
+}
+
+// Publishes a payload to the notification topic exchange, carrying the context's trace
+func (c *Consumer) publish(ctx context.Context, routingKey string, body []byte) error {
+	return c.ch.Publish(
+		"notification_exchange_topic",
+		routingKey,
+		false,
+		false,
+		amqp.Publishing{
+			Headers:      tracing.InjectAMQP(ctx, nil),
+			ContentType:  "application/json",
+			Body:         body,
+			DeliveryMode: amqp.Persistent,
+		},
+	)
+}
@@ -0,0 +1,72 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// Longest a worker waits in-process for outbound capacity before
+	// handing the notification to the scheduler
+	maxOutboundWait = 2 * time.Second
+	// Pause used when a provider throttles without a Retry-After
+	defaultProviderPause = 30 * time.Second
+)
+
+// Waits for an outbound slot for the notification's channel.
+// Returns false if the notification was deferred or requeued instead.
+func (c *Consumer) acquireOutbound(ctx context.Context, msg Message, notifMsg *NotificationMessage) (func(), bool) {
+	if c.throttle == nil {
+		return func() {}, true
+	}
+
+	for {
+		release, wait, err := c.throttle.Acquire(ctx, notifMsg.Type)
+		if err != nil {
+			// Pacing is best-effort; don't hold up delivery when Redis is down
+			c.logger(ctx).Error("Outbound throttle error", zap.Error(err))
+			return func() {}, true
+		}
+		if release != nil {
+			return release, true
+		}
+
+		if wait > maxOutboundWait {
+			until := time.Now().Add(wait)
+			reason := fmt.Sprintf("%s provider throttled until %s", notifMsg.Type, until.Format(time.RFC3339))
+			c.deferUntil(ctx, msg, notifMsg.NotificationID, until, reason)
+			return nil, false
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			c.logger(ctx).Warn("Timed out waiting for outbound capacity")
+			if err := msg.Delivery.Nack(false, true); err != nil {
+				c.logger(ctx).Error("Error sending Nack", zap.Error(err))
+			}
+			return nil, false
+		}
+	}
+}
+
+// Pauses the channel after the provider throttled us and defers the
+// notification until the provider accepts calls again
+func (c *Consumer) pauseOutbound(ctx context.Context, msg Message, notifMsg *NotificationMessage, retryAfter time.Duration) {
+	if retryAfter <= 0 {
+		retryAfter = defaultProviderPause
+	}
+
+	if c.throttle != nil {
+		if err := c.throttle.Pause(ctx, notifMsg.Type, retryAfter); err != nil {
+			c.logger(ctx).Error("Failed to pause channel", zap.Error(err))
+		}
+	}
+
+	until := time.Now().Add(retryAfter)
+	reason := fmt.Sprintf("%s provider rate limited, retrying at %s", notifMsg.Type, until.Format(time.RFC3339))
+	c.deferUntil(ctx, msg, notifMsg.NotificationID, until, reason)
+}
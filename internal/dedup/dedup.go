@@ -0,0 +1,83 @@
+package dedup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Marks a claimed key whose notification has not been stored yet
+const inFlight = "0"
+
+// Drops repeated notifications seen within a TTL window
+type Deduplicator struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// Creates a new Deduplicator
+func NewDeduplicator(addr string, ttl time.Duration) *Deduplicator {
+	client := redis.NewClient(&redis.Options{
+		Addr: addr,
+	})
+
+	return &Deduplicator{
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+// Builds the dedup key for a tenant's user from an explicit key, or from the content when empty
+func Key(tenantID, userID, dedupKey, notificationType, title, message string) string {
+	parts := []string{tenantID, userID, "key", dedupKey}
+	if dedupKey == "" {
+		parts = []string{tenantID, userID, "content", notificationType, title, message}
+	}
+
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return "dedup:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// Claims the key for a new notification.
+// Returns false and the original notification ID (0 if still being stored)
+// when the key was already claimed within the TTL.
+func (d *Deduplicator) Claim(ctx context.Context, key string) (bool, int64, error) {
+	claimed, err := d.client.SetNX(ctx, key, inFlight, d.ttl).Result()
+	if err != nil {
+		return false, 0, err
+	}
+	if claimed {
+		return true, 0, nil
+	}
+
+	value, err := d.client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		// Expired between SETNX and GET, treat as a duplicate of an unknown original
+		return false, 0, nil
+	}
+	if err != nil {
+		return false, 0, err
+	}
+
+	originalID, _ := strconv.ParseInt(value, 10, 64)
+	return false, originalID, nil
+}
+
+// Points a claimed key at the stored notification, keeping its TTL
+func (d *Deduplicator) Confirm(ctx context.Context, key string, notificationID int64) error {
+	return d.client.Set(ctx, key, notificationID, redis.KeepTTL).Err()
+}
+
+// Releases a claimed key so that a retry of a failed request is not dropped
+func (d *Deduplicator) Release(ctx context.Context, key string) error {
+	return d.client.Del(ctx, key).Err()
+}
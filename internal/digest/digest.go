@@ -0,0 +1,43 @@
+package digest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Max items listed in a summary before the rest are counted
+const maxListed = 10
+
+// Item is one notification collected into a digest
+type Item struct {
+	Title   string
+	Message string
+}
+
+// Renders the collected items into a single summary title and message
+func Render(key string, items []Item) (string, string) {
+	if len(items) == 1 {
+		return items[0].Title, items[0].Message
+	}
+
+	title := fmt.Sprintf("%d new notifications: %s", len(items), key)
+
+	var b strings.Builder
+	for i, item := range items {
+		if i == maxListed {
+			fmt.Fprintf(&b, "…and %d more\n", len(items)-maxListed)
+			break
+		}
+
+		switch {
+		case item.Title != "" && item.Message != "":
+			fmt.Fprintf(&b, "• %s: %s\n", item.Title, item.Message)
+		case item.Title != "":
+			fmt.Fprintf(&b, "• %s\n", item.Title)
+		default:
+			fmt.Fprintf(&b, "• %s\n", item.Message)
+		}
+	}
+
+	return title, strings.TrimSuffix(b.String(), "\n")
+}
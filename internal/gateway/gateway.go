@@ -0,0 +1,253 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Max accepted request body
+const maxBodyBytes = 1 << 20
+
+var marshalOptions = protojson.MarshalOptions{UseProtoNames: true}
+
+var unmarshalOptions = protojson.UnmarshalOptions{DiscardUnknown: true}
+
+// Gateway serves NotificationService over HTTP/JSON. Calls go through the
+// same interceptors as gRPC, so auth, tenants and rate limits behave alike.
+type Gateway struct {
+	routes      []route
+	interceptor grpc.UnaryServerInterceptor
+	log         *zap.Logger
+}
+
+// Creates a gateway in front of the notification server
+func NewGateway(server NotificationServer, logger *zap.Logger, interceptors ...grpc.UnaryServerInterceptor) *Gateway {
+	return &Gateway{
+		routes:      routes(server),
+		interceptor: chainUnary(interceptors),
+		log:         logger,
+	}
+}
+
+// Returns the HTTP handler for all routes and the OpenAPI document
+func (g *Gateway) Handler() http.Handler {
+	mux := http.NewServeMux()
+	for _, rt := range g.routes {
+		mux.HandleFunc(rt.method+" "+rt.pattern, g.serve(rt))
+	}
+
+	spec, err := json.MarshalIndent(g.OpenAPI(), "", "  ")
+	if err != nil {
+		g.log.Error("Failed to build OpenAPI document", zap.Error(err))
+	}
+	mux.HandleFunc("GET /openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write(spec); err != nil {
+			g.log.Error("Failed to write OpenAPI document", zap.Error(err))
+		}
+	})
+
+	return mux
+}
+
+// Decodes the request, runs the RPC and encodes its response
+func (g *Gateway) serve(rt route) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := rt.newRequest()
+
+		if r.Method != http.MethodGet {
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes))
+			if err != nil {
+				g.writeError(w, status.Error(codes.InvalidArgument, "failed to read body"))
+				return
+			}
+			if len(body) > 0 {
+				if err := unmarshalOptions.Unmarshal(body, req); err != nil {
+					g.writeError(w, status.Errorf(codes.InvalidArgument, "invalid JSON body: %v", err))
+					return
+				}
+			}
+		} else if err := bindQuery(r, req); err != nil {
+			g.writeError(w, err)
+			return
+		}
+		if err := bindPath(r, rt.pathFields, req); err != nil {
+			g.writeError(w, err)
+			return
+		}
+
+		stream := &headerStream{method: rt.fullMethod}
+		ctx := incomingContext(r)
+		ctx = grpc.NewContextWithServerTransportStream(ctx, stream)
+
+		resp, err := g.interceptor(
+			ctx,
+			req,
+			&grpc.UnaryServerInfo{FullMethod: rt.fullMethod},
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return rt.invoke(ctx, req.(proto.Message))
+			},
+		)
+
+		copyMetadata(w.Header(), stream.header)
+		copyMetadata(w.Header(), stream.trailer)
+		if err != nil {
+			g.writeError(w, err)
+			return
+		}
+
+		data, err := marshalOptions.Marshal(resp.(proto.Message))
+		if err != nil {
+			g.writeError(w, status.Errorf(codes.Internal, "failed to encode response: %v", err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write(data); err != nil {
+			g.log.Warn("Failed to write response", zap.String("method", rt.fullMethod), zap.Error(err))
+		}
+	}
+}
+
+// Sets path values on the request message's fields
+func bindPath(r *http.Request, fields []string, req proto.Message) error {
+	msg := req.ProtoReflect()
+	for _, name := range fields {
+		field := msg.Descriptor().Fields().ByName(protoreflect.Name(name))
+		value := r.PathValue(name)
+
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid %s: %q", name, value)
+		}
+		if err := checkRange(field, v); err != nil {
+			return err
+		}
+		msg.Set(field, int64Value(field, v))
+	}
+	return nil
+}
+
+// Sets query parameters of a GET request on the scalar fields they name
+func bindQuery(r *http.Request, req proto.Message) error {
+	msg := req.ProtoReflect()
+	for name, values := range r.URL.Query() {
+		field := msg.Descriptor().Fields().ByName(protoreflect.Name(name))
+		if field == nil || field.IsList() || field.IsMap() || field.Message() != nil {
+			return status.Errorf(codes.InvalidArgument, "unknown query parameter %q", name)
+		}
+		value := values[len(values)-1]
+
+		switch field.Kind() {
+		case protoreflect.StringKind:
+			msg.Set(field, protoreflect.ValueOfString(value))
+		case protoreflect.BoolKind:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return status.Errorf(codes.InvalidArgument, "invalid %s: %q", name, value)
+			}
+			msg.Set(field, protoreflect.ValueOfBool(b))
+		case protoreflect.Int32Kind, protoreflect.Int64Kind:
+			v, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return status.Errorf(codes.InvalidArgument, "invalid %s: %q", name, value)
+			}
+			if err := checkRange(field, v); err != nil {
+				return err
+			}
+			msg.Set(field, int64Value(field, v))
+		default:
+			return status.Errorf(codes.InvalidArgument, "unsupported query parameter %q", name)
+		}
+	}
+	return nil
+}
+
+// Carries HTTP headers as gRPC metadata and the TLS state as the gRPC peer,
+// so client identity from mTLS works the same as over gRPC
+func incomingContext(r *http.Request) context.Context {
+	md := metadata.MD{}
+	for key, values := range r.Header {
+		md.Append(strings.ToLower(key), values...)
+	}
+	ctx := metadata.NewIncomingContext(r.Context(), md)
+
+	p := &peer.Peer{Addr: remoteAddr(r.RemoteAddr)}
+	if r.TLS != nil {
+		p.AuthInfo = credentials.TLSInfo{
+			State:          *r.TLS,
+			CommonAuthInfo: credentials.CommonAuthInfo{SecurityLevel: credentials.PrivacyAndIntegrity},
+		}
+	}
+	return peer.NewContext(ctx, p)
+}
+
+// Copies response metadata such as rate limit state to HTTP headers
+func copyMetadata(h http.Header, md metadata.MD) {
+	for key, values := range md {
+		if len(values) > 0 {
+			h.Set(key, values[len(values)-1])
+		}
+	}
+}
+
+// Writes a gRPC error as a google.rpc.Status JSON body with a matching HTTP status
+func (g *Gateway) writeError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+	data, marshalErr := marshalOptions.Marshal(st.Proto())
+	if marshalErr != nil {
+		data = []byte(`{"code":13,"message":"failed to encode error"}`)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus(st.Code()))
+	if _, err := w.Write(data); err != nil {
+		g.log.Warn("Failed to write error response", zap.Error(err))
+	}
+}
+
+// Maps gRPC codes to HTTP statuses the way other gRPC gateways do
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.FailedPrecondition:
+		return http.StatusBadRequest
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,212 @@
+package gateway
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Builds the OpenAPI 3 document for the gateway. Schemas come from the
+// proto descriptors, so they always match notify.proto.
+func (g *Gateway) OpenAPI() map[string]interface{} {
+	schemas := map[string]interface{}{}
+	paths := map[string]map[string]interface{}{}
+
+	for _, rt := range g.routes {
+		req := rt.newRequest().ProtoReflect().Descriptor()
+		reply := rt.newReply().ProtoReflect().Descriptor()
+		addSchema(schemas, req)
+		addSchema(schemas, reply)
+
+		op := map[string]interface{}{
+			"operationId": rt.fullMethod[strings.LastIndex(rt.fullMethod, "/")+1:],
+			"summary":     rt.summary,
+			"responses": map[string]interface{}{
+				"200": jsonContent("OK", reply),
+				"default": map[string]interface{}{
+					"description": "Error",
+					"content": map[string]interface{}{
+						"application/json": map[string]interface{}{
+							"schema": map[string]interface{}{"$ref": "#/components/schemas/Status"},
+						},
+					},
+				},
+			},
+		}
+
+		params := []interface{}{}
+		for _, name := range rt.pathFields {
+			field := req.Fields().ByName(protoreflect.Name(name))
+			params = append(params, map[string]interface{}{
+				"name":     name,
+				"in":       "path",
+				"required": true,
+				"schema":   fieldSchema(field),
+			})
+		}
+		// GET requests take their other scalar fields from the query string
+		if rt.method == "GET" {
+			fields := req.Fields()
+			for i := 0; i < fields.Len(); i++ {
+				field := fields.Get(i)
+				if field.IsList() || field.IsMap() || field.Message() != nil || isPathField(rt, field) {
+					continue
+				}
+				params = append(params, map[string]interface{}{
+					"name":   string(field.Name()),
+					"in":     "query",
+					"schema": fieldSchema(field),
+				})
+			}
+		}
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+		if rt.method != "GET" {
+			op["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{
+						"schema": map[string]interface{}{"$ref": schemaRef(req)},
+					},
+				},
+			}
+		}
+
+		if paths[rt.pattern] == nil {
+			paths[rt.pattern] = map[string]interface{}{}
+		}
+		paths[rt.pattern][strings.ToLower(rt.method)] = op
+	}
+
+	// google.rpc.Status, as written for errors
+	schemas["Status"] = map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"code":    map[string]interface{}{"type": "integer", "format": "int32"},
+			"message": map[string]interface{}{"type": "string"},
+			"details": map[string]interface{}{
+				"type":  "array",
+				"items": map[string]interface{}{"type": "object", "additionalProperties": true},
+			},
+		},
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "GoNotify",
+			"version": "v1",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				"apiKey": map[string]interface{}{"type": "apiKey", "in": "header", "name": "x-api-key"},
+				"bearer": map[string]interface{}{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+		"security": []interface{}{
+			map[string]interface{}{"apiKey": []string{}},
+			map[string]interface{}{"bearer": []string{}},
+		},
+	}
+}
+
+// Reports whether the field is set from the route's path
+func isPathField(rt route, field protoreflect.FieldDescriptor) bool {
+	for _, name := range rt.pathFields {
+		if string(field.Name()) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Adds the message and every message it references to schemas
+func addSchema(schemas map[string]interface{}, md protoreflect.MessageDescriptor) {
+	name := string(md.Name())
+	if _, ok := schemas[name]; ok {
+		return
+	}
+
+	properties := map[string]interface{}{}
+	schemas[name] = map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		properties[string(field.Name())] = fieldSchema(field)
+
+		if field.IsMap() {
+			field = field.MapValue()
+		}
+		if field.Message() != nil {
+			addSchema(schemas, field.Message())
+		}
+	}
+}
+
+// Returns the JSON schema of a field as protojson encodes it
+func fieldSchema(field protoreflect.FieldDescriptor) map[string]interface{} {
+	if field.IsMap() {
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": scalarSchema(field.MapValue()),
+		}
+	}
+	if field.IsList() {
+		return map[string]interface{}{
+			"type":  "array",
+			"items": scalarSchema(field),
+		}
+	}
+	return scalarSchema(field)
+}
+
+func scalarSchema(field protoreflect.FieldDescriptor) map[string]interface{} {
+	switch field.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return map[string]interface{}{"$ref": schemaRef(field.Message())}
+	case protoreflect.BoolKind:
+		return map[string]interface{}{"type": "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		// protojson writes 64-bit integers as strings
+		return map[string]interface{}{"type": "string", "format": "int64"}
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return map[string]interface{}{"type": "number"}
+	case protoreflect.BytesKind:
+		return map[string]interface{}{"type": "string", "format": "byte"}
+	case protoreflect.EnumKind:
+		values := field.Enum().Values()
+		names := make([]string, 0, values.Len())
+		for i := 0; i < values.Len(); i++ {
+			names = append(names, string(values.Get(i).Name()))
+		}
+		return map[string]interface{}{"type": "string", "enum": names}
+	default:
+		return map[string]interface{}{"type": "string"}
+	}
+}
+
+func schemaRef(md protoreflect.MessageDescriptor) string {
+	return "#/components/schemas/" + string(md.Name())
+}
+
+func jsonContent(description string, md protoreflect.MessageDescriptor) map[string]interface{} {
+	return map[string]interface{}{
+		"description": description,
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{
+				"schema": map[string]interface{}{"$ref": schemaRef(md)},
+			},
+		},
+	}
+}
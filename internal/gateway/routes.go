@@ -0,0 +1,126 @@
+package gateway
+
+import (
+	"context"
+	"math"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	pb "github.com/officiallysidsingh/go-notify/api/generated"
+)
+
+// NotificationServer is the gRPC service the gateway calls into
+type NotificationServer = pb.NotificationServiceServer
+
+// route maps an HTTP method and path to a NotificationService RPC
+type route struct {
+	method     string
+	pattern    string
+	pathFields []string // Path wildcards, named after the request fields they set
+	fullMethod string
+	summary    string
+	newRequest func() proto.Message
+	newReply   func() proto.Message
+	invoke     func(ctx context.Context, req proto.Message) (proto.Message, error)
+}
+
+// The HTTP API. Keep in sync with NotificationService in notify.proto.
+func routes(s NotificationServer) []route {
+	return []route{
+		{
+			method:     "POST",
+			pattern:    "/v1/notifications",
+			fullMethod: pb.NotificationService_SendNotification_FullMethodName,
+			summary:    "Send a notification",
+			newRequest: func() proto.Message { return &pb.NotificationRequest{} },
+			newReply:   func() proto.Message { return &pb.NotificationResponse{} },
+			invoke:     unary(s.SendNotification),
+		},
+		{
+			method:     "POST",
+			pattern:    "/v1/notifications/batch",
+			fullMethod: pb.NotificationService_SendNotifications_FullMethodName,
+			summary:    "Send several notifications",
+			newRequest: func() proto.Message { return &pb.BatchNotificationRequest{} },
+			newReply:   func() proto.Message { return &pb.BatchNotificationResponse{} },
+			invoke:     unary(s.SendNotifications),
+		},
+		{
+			method:     "GET",
+			pattern:    "/v1/notifications",
+			fullMethod: pb.NotificationService_ListNotifications_FullMethodName,
+			summary:    "List notifications with filters, one page at a time",
+			newRequest: func() proto.Message { return &pb.ListNotificationsRequest{} },
+			newReply:   func() proto.Message { return &pb.ListNotificationsResponse{} },
+			invoke:     unary(s.ListNotifications),
+		},
+		{
+			method:     "GET",
+			pattern:    "/v1/notifications/{notification_id}",
+			pathFields: []string{"notification_id"},
+			fullMethod: pb.NotificationService_GetNotificationStatus_FullMethodName,
+			summary:    "Get the status of a notification",
+			newRequest: func() proto.Message { return &pb.StatusRequest{} },
+			newReply:   func() proto.Message { return &pb.StatusResponse{} },
+			invoke:     unary(s.GetNotificationStatus),
+		},
+		{
+			method:     "POST",
+			pattern:    "/v1/notifications/{notification_id}/cancel",
+			pathFields: []string{"notification_id"},
+			fullMethod: pb.NotificationService_CancelNotification_FullMethodName,
+			summary:    "Cancel a notification that has not been sent yet",
+			newRequest: func() proto.Message { return &pb.CancelNotificationRequest{} },
+			newReply:   func() proto.Message { return &pb.CancelNotificationResponse{} },
+			invoke:     unary(s.CancelNotification),
+		},
+	}
+}
+
+// Adapts a typed RPC method to a route's invoke function
+func unary[Req, Reply proto.Message](
+	fn func(context.Context, Req) (Reply, error),
+) func(context.Context, proto.Message) (proto.Message, error) {
+	return func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return fn(ctx, req.(Req))
+	}
+}
+
+// Returns the value for an integer field, checking it fits
+func int64Value(field protoreflect.FieldDescriptor, v int64) protoreflect.Value {
+	if field.Kind() == protoreflect.Int32Kind {
+		return protoreflect.ValueOfInt32(int32(v))
+	}
+	return protoreflect.ValueOfInt64(v)
+}
+
+// Checks a path value fits its field
+func checkRange(field protoreflect.FieldDescriptor, v int64) error {
+	if field.Kind() == protoreflect.Int32Kind && (v < math.MinInt32 || v > math.MaxInt32) {
+		return status.Errorf(codes.InvalidArgument, "%s out of range", field.Name())
+	}
+	return nil
+}
+
+// Runs interceptors in order around the handler, like grpc.ChainUnaryInterceptor
+func chainUnary(interceptors []grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		next := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, inner := interceptors[i], next
+			next = func(ctx context.Context, req interface{}) (interface{}, error) {
+				return interceptor(ctx, req, info, inner)
+			}
+		}
+		return next(ctx, req)
+	}
+}
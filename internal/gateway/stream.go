@@ -0,0 +1,42 @@
+package gateway
+
+import (
+	"net"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// headerStream stands in for the gRPC transport stream so handlers can set
+// response headers and trailers, which the gateway turns into HTTP headers
+type headerStream struct {
+	method  string
+	header  metadata.MD
+	trailer metadata.MD
+}
+
+func (s *headerStream) Method() string {
+	return s.method
+}
+
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *headerStream) SendHeader(md metadata.MD) error {
+	return s.SetHeader(md)
+}
+
+func (s *headerStream) SetTrailer(md metadata.MD) error {
+	s.trailer = metadata.Join(s.trailer, md)
+	return nil
+}
+
+// Parses the client address of an HTTP request
+func remoteAddr(addr string) net.Addr {
+	tcpAddr, err := net.ResolveTCPAddr("tcp", addr)
+	if err != nil {
+		return &net.IPAddr{}
+	}
+	return tcpAddr
+}
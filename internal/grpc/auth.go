@@ -0,0 +1,125 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/officiallysidsingh/go-notify/api/generated"
+	"github.com/officiallysidsingh/go-notify/internal/auth"
+	"github.com/officiallysidsingh/go-notify/internal/logging"
+)
+
+// Scope required by each NotificationService RPC.
+// RPCs missing here require the admin scope.
+var methodScopes = map[string]string{
+	pb.NotificationService_SendNotification_FullMethodName:        auth.ScopeSend,
+	pb.NotificationService_SendNotifications_FullMethodName:       auth.ScopeSend,
+	pb.NotificationService_CancelNotification_FullMethodName:      auth.ScopeSend,
+	pb.NotificationService_MarkInboxRead_FullMethodName:           auth.ScopeSend,
+	pb.NotificationService_MarkAllInboxRead_FullMethodName:        auth.ScopeSend,
+	pb.NotificationService_ArchiveInboxItems_FullMethodName:       auth.ScopeSend,
+	pb.NotificationService_GetNotificationStatus_FullMethodName:   auth.ScopeRead,
+	pb.NotificationService_WatchNotificationStatus_FullMethodName: auth.ScopeRead,
+	pb.NotificationService_ListNotifications_FullMethodName:       auth.ScopeRead,
+	pb.NotificationService_GetUserLocale_FullMethodName:           auth.ScopeRead,
+	pb.NotificationService_ListContacts_FullMethodName:            auth.ScopeRead,
+	pb.NotificationService_GetPreferences_FullMethodName:          auth.ScopeRead,
+	pb.NotificationService_GetQuietHours_FullMethodName:           auth.ScopeRead,
+	pb.NotificationService_GetInbox_FullMethodName:                auth.ScopeRead,
+	pb.NotificationService_GetUnreadCount_FullMethodName:          auth.ScopeRead,
+}
+
+// Prefix of the RPCs guarded by the auth interceptor; health and reflection stay open
+const notificationServicePrefix = "/notify.NotificationService/"
+
+// Authenticates every NotificationService call and checks it has the RPC's scope
+func AuthUnaryInterceptor(authenticator *auth.Authenticator, logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		ctx, err := authorize(ctx, authenticator, info.FullMethod, logger)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// Authenticates every NotificationService stream and checks it has the RPC's scope
+func AuthStreamInterceptor(authenticator *auth.Authenticator, logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		ctx, err := authorize(ss.Context(), authenticator, info.FullMethod, logger)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// Returns ctx carrying the authenticated principal, or an error if the
+// caller is unknown or lacks the method's scope
+func authorize(
+	ctx context.Context,
+	authenticator *auth.Authenticator,
+	fullMethod string,
+	logger *zap.Logger,
+) (context.Context, error) {
+	if !strings.HasPrefix(fullMethod, notificationServicePrefix) {
+		return ctx, nil
+	}
+
+	principal, err := authenticator.Authenticate(ctx)
+	if errors.Is(err, auth.ErrUnauthenticated) {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err != nil {
+		logging.WithTrace(ctx, logger).Error("Authentication failed", zap.String("method", fullMethod), zap.Error(err))
+		return nil, status.Error(codes.Internal, "authentication error")
+	}
+
+	scope, ok := methodScopes[fullMethod]
+	if !ok {
+		scope = auth.ScopeAdmin
+	}
+	if !principal.HasScope(scope) {
+		fields := append(principalFields(principal), zap.String("method", fullMethod), zap.String("scope", scope))
+		logging.WithTrace(ctx, logger).Warn("Denied call missing scope", fields...)
+		return nil, status.Errorf(codes.PermissionDenied, "%s requires scope %q", fullMethod, scope)
+	}
+
+	return auth.NewContext(ctx, principal), nil
+}
+
+// contextStream replaces a stream's context, as interceptors can't otherwise
+// pass values on to stream handlers
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// Describes a principal, and the client certificate behind it, for audit logs
+func principalFields(p *auth.Principal) []zap.Field {
+	fields := []zap.Field{zap.String("subject", p.Subject)}
+	if p.Identity != nil {
+		fields = append(fields, zap.String("certificate", p.Identity.Name()), zap.String("serial", p.Identity.SerialNumber))
+	}
+	return fields
+}
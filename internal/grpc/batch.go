@@ -0,0 +1,49 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/officiallysidsingh/go-notify/api/generated"
+)
+
+// Max notifications accepted in one batch
+const maxBatchSize = 100
+
+// Sends several notifications, each accepted or rejected on its own
+func (s *NotificationServer) SendNotifications(
+	ctx context.Context,
+	req *pb.BatchNotificationRequest,
+) (
+	*pb.BatchNotificationResponse,
+	error,
+) {
+	if len(req.Notifications) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "notifications is required")
+	}
+	if len(req.Notifications) > maxBatchSize {
+		return nil, status.Error(
+			codes.InvalidArgument,
+			fmt.Sprintf("at most %d notifications can be sent in one batch", maxBatchSize),
+		)
+	}
+
+	resp := &pb.BatchNotificationResponse{
+		Results: make([]*pb.NotificationResponse, 0, len(req.Notifications)),
+	}
+	for _, notification := range req.Notifications {
+		result, err := s.sendNotification(ctx, notification, false)
+		if result == nil {
+			result = &pb.NotificationResponse{}
+		}
+		if err != nil && result.Error == "" {
+			result.Error = err.Error()
+		}
+		resp.Results = append(resp.Results, result)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,61 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/officiallysidsingh/go-notify/internal/callback"
+	"github.com/officiallysidsingh/go-notify/internal/logging"
+	"github.com/officiallysidsingh/go-notify/internal/tenant"
+)
+
+// Checks the callback URL, and that the tenant can sign callbacks
+func validateCallback(t *tenant.Tenant, callbackURL string) error {
+	if callbackURL == "" {
+		return nil
+	}
+	if err := callback.ValidateURL(callbackURL); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if t.CallbackSecret == "" {
+		return status.Errorf(codes.FailedPrecondition, "tenant %s has no callback secret configured", t.ID)
+	}
+	return nil
+}
+
+// Queues a status event for the notification's callback_url, if it has one.
+// Workers deliver it with the other pending callbacks.
+func (s *NotificationServer) queueCallback(ctx context.Context, notificationID int64, status, reason string) {
+	logger := s.logger(ctx).With(logging.NotificationID(notificationID))
+
+	userID, callbackURL, err := s.db.GetNotificationCallback(ctx, notificationID)
+	if err != nil {
+		logger.Error("Failed to look up callback", zap.Error(err))
+		return
+	}
+	if callbackURL == "" {
+		return
+	}
+
+	payload, err := json.Marshal(callback.Event{
+		NotificationID: notificationID,
+		TenantID:       tenant.FromContext(ctx),
+		UserID:         userID,
+		Status:         status,
+		StatusReason:   reason,
+		OccurredAt:     time.Now().UTC(),
+	})
+	if err != nil {
+		logger.Error("Failed to encode callback", zap.Error(err))
+		return
+	}
+
+	if err := s.db.InsertCallback(ctx, notificationID, callbackURL, payload); err != nil {
+		logger.Error("Failed to queue callback", zap.Error(err))
+	}
+}
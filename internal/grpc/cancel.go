@@ -0,0 +1,42 @@
+package grpc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/officiallysidsingh/go-notify/api/generated"
+	"github.com/officiallysidsingh/go-notify/internal/logging"
+	"github.com/officiallysidsingh/go-notify/internal/repository"
+)
+
+// Cancels a notification that has not been sent yet
+func (s *NotificationServer) CancelNotification(
+	ctx context.Context,
+	req *pb.CancelNotificationRequest,
+) (
+	*pb.CancelNotificationResponse,
+	error,
+) {
+	current, err := s.db.CancelNotification(ctx, req.NotificationId)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = status.Errorf(codes.NotFound, "notification %d not found", req.NotificationId)
+		return &pb.CancelNotificationResponse{Error: err.Error()}, err
+	}
+	if errors.Is(err, repository.ErrNotCancellable) {
+		err = status.Errorf(codes.FailedPrecondition, "notification %d is already %s", req.NotificationId, current)
+		return &pb.CancelNotificationResponse{Status: current, Error: err.Error()}, err
+	}
+	if err != nil {
+		s.logger(ctx).Error("Failed to cancel notification", logging.NotificationID(req.NotificationId), zap.Error(err))
+		return &pb.CancelNotificationResponse{Error: err.Error()}, err
+	}
+
+	s.logger(ctx).Info("Cancelled notification", logging.NotificationID(req.NotificationId))
+	s.queueCallback(ctx, req.NotificationId, current, "cancelled by caller")
+	return &pb.CancelNotificationResponse{Success: true, Status: current}, nil
+}
@@ -0,0 +1,132 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+
+	pb "github.com/officiallysidsingh/go-notify/api/generated"
+	"github.com/officiallysidsingh/go-notify/internal/logging"
+	"github.com/officiallysidsingh/go-notify/internal/recipients"
+	"github.com/officiallysidsingh/go-notify/internal/repository"
+)
+
+// Registers a new contact point for a user
+func (s *NotificationServer) AddContact(
+	ctx context.Context,
+	req *pb.AddContactRequest,
+) (
+	*pb.ContactResponse,
+	error,
+) {
+	if req.UserId == "" {
+		return &pb.ContactResponse{Error: "user_id is required"}, errors.New("user_id is required")
+	}
+	if err := recipients.Validate(req.Kind, req.Address); err != nil {
+		return &pb.ContactResponse{Error: err.Error()}, err
+	}
+
+	contact, err := s.db.InsertContact(ctx, req.UserId, req.Kind, req.Address, req.Label)
+	if err != nil {
+		s.logger(ctx).Error("Failed to add contact", logging.UserID(req.UserId), zap.Error(err))
+		return &pb.ContactResponse{Error: err.Error()}, err
+	}
+
+	return &pb.ContactResponse{Contact: toPBContact(contact)}, nil
+}
+
+// Lists a user's contact points
+func (s *NotificationServer) ListContacts(
+	ctx context.Context,
+	req *pb.ListContactsRequest,
+) (
+	*pb.ListContactsResponse,
+	error,
+) {
+	if req.UserId == "" {
+		return &pb.ListContactsResponse{Error: "user_id is required"}, errors.New("user_id is required")
+	}
+
+	contacts, err := s.db.ListContacts(ctx, req.UserId, req.Kind)
+	if err != nil {
+		s.logger(ctx).Error("Failed to list contacts", logging.UserID(req.UserId), zap.Error(err))
+		return &pb.ListContactsResponse{Error: err.Error()}, err
+	}
+
+	resp := &pb.ListContactsResponse{Contacts: make([]*pb.Contact, 0, len(contacts))}
+	for i := range contacts {
+		resp.Contacts = append(resp.Contacts, toPBContact(&contacts[i]))
+	}
+	return resp, nil
+}
+
+// Updates a contact's address, label or verification state
+func (s *NotificationServer) UpdateContact(
+	ctx context.Context,
+	req *pb.UpdateContactRequest,
+) (
+	*pb.ContactResponse,
+	error,
+) {
+	contact, err := s.db.GetContact(ctx, req.Id)
+	if err != nil {
+		return &pb.ContactResponse{Error: err.Error()}, err
+	}
+
+	if req.Address != nil {
+		if err := recipients.Validate(contact.Kind, req.GetAddress()); err != nil {
+			return &pb.ContactResponse{Error: err.Error()}, err
+		}
+		contact.Address = req.GetAddress()
+	}
+	if req.Label != nil {
+		contact.Label = req.GetLabel()
+	}
+	if req.Verified != nil {
+		contact.Verified = req.GetVerified()
+	}
+
+	updated, err := s.db.UpdateContact(ctx, contact)
+	if err != nil {
+		s.logger(ctx).Error("Failed to update contact", zap.Int64("contact_id", req.Id), zap.Error(err))
+		return &pb.ContactResponse{Error: err.Error()}, err
+	}
+
+	return &pb.ContactResponse{Contact: toPBContact(updated)}, nil
+}
+
+// Removes a contact point
+func (s *NotificationServer) DeleteContact(
+	ctx context.Context,
+	req *pb.DeleteContactRequest,
+) (
+	*pb.DeleteContactResponse,
+	error,
+) {
+	if err := s.db.DeleteContact(ctx, req.Id); err != nil {
+		s.logger(ctx).Error("Failed to delete contact", zap.Int64("contact_id", req.Id), zap.Error(err))
+		return &pb.DeleteContactResponse{Success: false, Error: err.Error()}, err
+	}
+
+	return &pb.DeleteContactResponse{Success: true}, nil
+}
+
+// Converts a repository contact into its protobuf form
+func toPBContact(contact *repository.Contact) *pb.Contact {
+	var verifiedAt string
+	if contact.VerifiedAt.Valid {
+		verifiedAt = contact.VerifiedAt.Time.Format(time.RFC3339)
+	}
+
+	return &pb.Contact{
+		Id:         contact.ID,
+		UserId:     contact.UserID,
+		Kind:       contact.Kind,
+		Address:    contact.Address,
+		Label:      contact.Label,
+		Verified:   contact.Verified,
+		VerifiedAt: verifiedAt,
+	}
+}
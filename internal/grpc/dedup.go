@@ -0,0 +1,69 @@
+package grpc
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	pb "github.com/officiallysidsingh/go-notify/api/generated"
+	"github.com/officiallysidsingh/go-notify/internal/dedup"
+	"github.com/officiallysidsingh/go-notify/internal/logging"
+	"github.com/officiallysidsingh/go-notify/internal/tenant"
+)
+
+// Claims the request's dedup key. Returns the response for a duplicate,
+// or the claimed key ("" when deduplication is off or unavailable).
+func (s *NotificationServer) claimDedupKey(
+	ctx context.Context,
+	req *pb.NotificationRequest,
+) (string, *pb.NotificationResponse) {
+	if s.dedup == nil {
+		return "", nil
+	}
+
+	key := dedup.Key(tenant.FromContext(ctx), req.UserId, req.DedupKey, req.Type, req.Title, req.Message)
+	claimed, originalID, err := s.dedup.Claim(ctx, key)
+	if err != nil {
+		// Deduplication is best-effort and must not block delivery
+		s.logger(ctx).Error("Dedup error", logging.UserID(req.UserId), zap.Error(err))
+		return "", nil
+	}
+	if claimed {
+		return key, nil
+	}
+
+	notificationsDeduplicated.Inc()
+	s.logger(ctx).Info("Dropped duplicate notification", logging.UserID(req.UserId), logging.NotificationID(originalID))
+
+	return "", &pb.NotificationResponse{
+		Success:        true,
+		NotificationId: originalID,
+		Duplicate:      true,
+	}
+}
+
+// Points the claimed key at the stored notification
+func (s *NotificationServer) confirmDedupKey(ctx context.Context, key string, notificationID int64) {
+	if key == "" {
+		return
+	}
+	if err := s.dedup.Confirm(ctx, key, notificationID); err != nil {
+		s.logger(ctx).Error("Failed to confirm dedup key", logging.NotificationID(notificationID), zap.Error(err))
+	}
+}
+
+// Frees the claimed key after a failed request
+func (s *NotificationServer) releaseDedupKey(key string) {
+	if key == "" {
+		return
+	}
+
+	// The request context may already be cancelled
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := s.dedup.Release(ctx, key); err != nil {
+		s.log.Error("Failed to release dedup key", zap.Error(err))
+	}
+}
@@ -0,0 +1,191 @@
+package grpc
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/officiallysidsingh/go-notify/api/generated"
+	"github.com/officiallysidsingh/go-notify/internal/logging"
+	"github.com/officiallysidsingh/go-notify/internal/repository"
+)
+
+// Max notification IDs per mark read or archive request
+const maxInboxUpdateIDs = 500
+
+// Returns one page of a user's in-app inbox, newest first
+func (s *NotificationServer) GetInbox(
+	ctx context.Context,
+	req *pb.GetInboxRequest,
+) (
+	*pb.GetInboxResponse,
+	error,
+) {
+	if req.UserId == "" {
+		err := status.Error(codes.InvalidArgument, "user_id is required")
+		return &pb.GetInboxResponse{Error: err.Error()}, err
+	}
+
+	filter := repository.InboxFilter{
+		UnreadOnly: req.UnreadOnly,
+		Archived:   req.Archived,
+	}
+	limit, err := pageLimit(req.PageSize)
+	if err != nil {
+		return &pb.GetInboxResponse{Error: err.Error()}, err
+	}
+	if req.PageToken != "" {
+		token, err := decodePageToken(req.PageToken)
+		if err != nil {
+			err = status.Error(codes.InvalidArgument, "invalid page_token")
+			return &pb.GetInboxResponse{Error: err.Error()}, err
+		}
+		filter.After = &repository.NotificationCursor{CreatedAt: token.CreatedAt, ID: token.ID}
+	}
+
+	// One extra row tells whether another page follows
+	filter.Limit = limit + 1
+
+	items, err := s.db.ListInbox(ctx, req.UserId, filter)
+	if err != nil {
+		s.logger(ctx).Error("Failed to get inbox", logging.UserID(req.UserId), zap.Error(err))
+		return &pb.GetInboxResponse{Error: err.Error()}, err
+	}
+
+	resp := &pb.GetInboxResponse{}
+	if len(items) > limit {
+		items = items[:limit]
+		last := items[len(items)-1]
+		resp.NextPageToken = encodePageToken(pageToken{CreatedAt: last.CreatedAt, ID: last.NotificationID})
+	}
+
+	resp.Items = make([]*pb.InboxItem, 0, len(items))
+	for _, item := range items {
+		resp.Items = append(resp.Items, &pb.InboxItem{
+			NotificationId: item.NotificationID,
+			Title:          item.Title,
+			Message:        item.Message,
+			Category:       item.Category.String,
+			Priority:       item.Priority.String,
+			CreatedAt:      item.CreatedAt.Format(time.RFC3339),
+			ReadAt:         formatNullTime(item.ReadAt),
+			ArchivedAt:     formatNullTime(item.ArchivedAt),
+		})
+	}
+	return resp, nil
+}
+
+// Returns how many unread items are in a user's inbox
+func (s *NotificationServer) GetUnreadCount(
+	ctx context.Context,
+	req *pb.GetUnreadCountRequest,
+) (
+	*pb.UnreadCountResponse,
+	error,
+) {
+	if req.UserId == "" {
+		err := status.Error(codes.InvalidArgument, "user_id is required")
+		return &pb.UnreadCountResponse{Error: err.Error()}, err
+	}
+
+	counts, err := s.db.CountUnread(ctx, req.UserId)
+	if err != nil {
+		s.logger(ctx).Error("Failed to count unread inbox items", logging.UserID(req.UserId), zap.Error(err))
+		return &pb.UnreadCountResponse{UserId: req.UserId, Error: err.Error()}, err
+	}
+
+	resp := &pb.UnreadCountResponse{UserId: req.UserId, ByCategory: counts}
+	for _, count := range counts {
+		resp.Unread += count
+	}
+	return resp, nil
+}
+
+// Marks inbox items as read
+func (s *NotificationServer) MarkInboxRead(
+	ctx context.Context,
+	req *pb.MarkInboxReadRequest,
+) (
+	*pb.InboxUpdateResponse,
+	error,
+) {
+	if err := validateInboxUpdate(req.UserId, req.NotificationIds); err != nil {
+		return &pb.InboxUpdateResponse{Error: err.Error()}, err
+	}
+
+	updated, err := s.db.MarkInboxRead(ctx, req.UserId, req.NotificationIds)
+	if err != nil {
+		s.logger(ctx).Error("Failed to mark inbox items read", logging.UserID(req.UserId), zap.Error(err))
+		return &pb.InboxUpdateResponse{Error: err.Error()}, err
+	}
+
+	return &pb.InboxUpdateResponse{Updated: updated}, nil
+}
+
+// Marks every unread item in a user's inbox as read
+func (s *NotificationServer) MarkAllInboxRead(
+	ctx context.Context,
+	req *pb.MarkAllInboxReadRequest,
+) (
+	*pb.InboxUpdateResponse,
+	error,
+) {
+	if req.UserId == "" {
+		err := status.Error(codes.InvalidArgument, "user_id is required")
+		return &pb.InboxUpdateResponse{Error: err.Error()}, err
+	}
+
+	updated, err := s.db.MarkInboxRead(ctx, req.UserId, nil)
+	if err != nil {
+		s.logger(ctx).Error("Failed to mark inbox read", logging.UserID(req.UserId), zap.Error(err))
+		return &pb.InboxUpdateResponse{Error: err.Error()}, err
+	}
+
+	return &pb.InboxUpdateResponse{Updated: updated}, nil
+}
+
+// Moves inbox items to the user's archive
+func (s *NotificationServer) ArchiveInboxItems(
+	ctx context.Context,
+	req *pb.ArchiveInboxItemsRequest,
+) (
+	*pb.InboxUpdateResponse,
+	error,
+) {
+	if err := validateInboxUpdate(req.UserId, req.NotificationIds); err != nil {
+		return &pb.InboxUpdateResponse{Error: err.Error()}, err
+	}
+
+	updated, err := s.db.ArchiveInboxItems(ctx, req.UserId, req.NotificationIds)
+	if err != nil {
+		s.logger(ctx).Error("Failed to archive inbox items", logging.UserID(req.UserId), zap.Error(err))
+		return &pb.InboxUpdateResponse{Error: err.Error()}, err
+	}
+
+	return &pb.InboxUpdateResponse{Updated: updated}, nil
+}
+
+// Checks a request that changes specific inbox items
+func validateInboxUpdate(userID string, ids []int64) error {
+	switch {
+	case userID == "":
+		return status.Error(codes.InvalidArgument, "user_id is required")
+	case len(ids) == 0:
+		return status.Error(codes.InvalidArgument, "notification_ids is required")
+	case len(ids) > maxInboxUpdateIDs:
+		return status.Errorf(codes.InvalidArgument, "at most %d notification_ids per request", maxInboxUpdateIDs)
+	}
+	return nil
+}
+
+// Formats a nullable timestamp as RFC 3339, or "" when unset
+func formatNullTime(t sql.NullTime) string {
+	if !t.Valid {
+		return ""
+	}
+	return t.Time.Format(time.RFC3339)
+}
@@ -0,0 +1,160 @@
+package grpc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/officiallysidsingh/go-notify/api/generated"
+	"github.com/officiallysidsingh/go-notify/internal/logging"
+	"github.com/officiallysidsingh/go-notify/internal/repository"
+)
+
+// Page sizes for ListNotifications
+const (
+	defaultListPageSize = 50
+	maxListPageSize     = 200
+)
+
+// pageToken is the opaque cursor handed out as next_page_token
+type pageToken struct {
+	CreatedAt time.Time `json:"t"`
+	ID        int64     `json:"id"`
+	Ascending bool      `json:"asc,omitempty"`
+}
+
+// Lists the tenant's notifications with filters and cursor pagination
+func (s *NotificationServer) ListNotifications(
+	ctx context.Context,
+	req *pb.ListNotificationsRequest,
+) (
+	*pb.ListNotificationsResponse,
+	error,
+) {
+	filter, err := listFilter(req)
+	if err != nil {
+		return &pb.ListNotificationsResponse{Error: err.Error()}, err
+	}
+
+	// One extra row tells whether another page follows
+	limit := filter.Limit
+	filter.Limit++
+
+	records, err := s.db.ListNotifications(ctx, filter)
+	if err != nil {
+		s.logger(ctx).Error("Failed to list notifications", logging.UserID(req.UserId), zap.Error(err))
+		return &pb.ListNotificationsResponse{Error: err.Error()}, err
+	}
+
+	resp := &pb.ListNotificationsResponse{}
+	if len(records) > limit {
+		records = records[:limit]
+		last := records[len(records)-1]
+		resp.NextPageToken = encodePageToken(pageToken{
+			CreatedAt: last.CreatedAt,
+			ID:        last.ID,
+			Ascending: filter.Ascending,
+		})
+	}
+
+	resp.Notifications = make([]*pb.NotificationSummary, 0, len(records))
+	for _, r := range records {
+		resp.Notifications = append(resp.Notifications, &pb.NotificationSummary{
+			Id:           r.ID,
+			UserId:       r.UserID,
+			Type:         r.Type.String,
+			Category:     r.Category.String,
+			Message:      r.Message,
+			Status:       r.Status.String,
+			StatusReason: r.StatusReason.String,
+			Caller:       r.Caller.String,
+			CreatedAt:    r.CreatedAt.Format(time.RFC3339),
+			ReadAt:       formatNullTime(r.ReadAt),
+		})
+	}
+	return resp, nil
+}
+
+// Validates the request into a repository filter
+func listFilter(req *pb.ListNotificationsRequest) (repository.NotificationFilter, error) {
+	filter := repository.NotificationFilter{
+		UserID: req.UserId,
+		Status: req.Status,
+		Type:   req.Type,
+	}
+
+	var err error
+	if filter.Limit, err = pageLimit(req.PageSize); err != nil {
+		return filter, err
+	}
+
+	switch req.Order {
+	case "", "desc":
+	case "asc":
+		filter.Ascending = true
+	default:
+		return filter, status.Errorf(codes.InvalidArgument, "order must be \"asc\" or \"desc\", got %q", req.Order)
+	}
+
+	if req.CreatedAfter != "" {
+		if filter.CreatedAfter, err = time.Parse(time.RFC3339, req.CreatedAfter); err != nil {
+			return filter, status.Error(codes.InvalidArgument, "created_after must be an RFC 3339 timestamp")
+		}
+	}
+	if req.CreatedBefore != "" {
+		if filter.CreatedBefore, err = time.Parse(time.RFC3339, req.CreatedBefore); err != nil {
+			return filter, status.Error(codes.InvalidArgument, "created_before must be an RFC 3339 timestamp")
+		}
+	}
+
+	if req.PageToken != "" {
+		token, err := decodePageToken(req.PageToken)
+		if err != nil {
+			return filter, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+		if token.Ascending != filter.Ascending {
+			return filter, status.Error(codes.InvalidArgument, "page_token was issued for a different order")
+		}
+		filter.After = &repository.NotificationCursor{CreatedAt: token.CreatedAt, ID: token.ID}
+	}
+
+	return filter, nil
+}
+
+// Applies the default and maximum to a requested page size
+func pageLimit(size int32) (int, error) {
+	switch {
+	case size < 0:
+		return 0, status.Error(codes.InvalidArgument, "page_size must not be negative")
+	case size == 0:
+		return defaultListPageSize, nil
+	case size > maxListPageSize:
+		return maxListPageSize, nil
+	}
+	return int(size), nil
+}
+
+// Encodes a cursor as URL-safe base64 JSON
+func encodePageToken(token pageToken) string {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decodes a cursor made by encodePageToken
+func decodePageToken(raw string) (pageToken, error) {
+	var token pageToken
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return token, err
+	}
+	err = json.Unmarshal(data, &token)
+	return token, err
+}
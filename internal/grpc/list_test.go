@@ -0,0 +1,196 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/officiallysidsingh/go-notify/api/generated"
+	"github.com/officiallysidsingh/go-notify/internal/repository"
+)
+
+func TestPageTokenRoundTrip(t *testing.T) {
+	tests := []pageToken{
+		{CreatedAt: time.Date(2025, 4, 5, 9, 30, 0, 123456000, time.UTC), ID: 42},
+		{CreatedAt: time.Date(2025, 4, 5, 9, 30, 0, 0, time.UTC), ID: 7, Ascending: true},
+	}
+
+	for _, want := range tests {
+		got, err := decodePageToken(encodePageToken(want))
+		require.NoError(t, err)
+		assert.True(t, want.CreatedAt.Equal(got.CreatedAt))
+		assert.Equal(t, want.ID, got.ID)
+		assert.Equal(t, want.Ascending, got.Ascending)
+	}
+}
+
+func TestDecodePageTokenInvalid(t *testing.T) {
+	for _, raw := range []string{"not base64!", "bm90IGpzb24"} {
+		_, err := decodePageToken(raw)
+		assert.Error(t, err, raw)
+	}
+}
+
+func TestListFilter(t *testing.T) {
+	created := time.Date(2025, 4, 5, 9, 0, 0, 0, time.UTC)
+	descToken := encodePageToken(pageToken{CreatedAt: created, ID: 10})
+	ascToken := encodePageToken(pageToken{CreatedAt: created, ID: 10, Ascending: true})
+
+	tests := []struct {
+		name     string
+		req      *pb.ListNotificationsRequest
+		want     repository.NotificationFilter
+		wantCode codes.Code
+	}{
+		{
+			name: "defaults",
+			req:  &pb.ListNotificationsRequest{UserId: "u1", Status: "sent", Type: "email"},
+			want: repository.NotificationFilter{UserID: "u1", Status: "sent", Type: "email", Limit: defaultListPageSize},
+		},
+		{
+			name: "page size capped",
+			req:  &pb.ListNotificationsRequest{PageSize: 1000},
+			want: repository.NotificationFilter{Limit: maxListPageSize},
+		},
+		{
+			name:     "negative page size",
+			req:      &pb.ListNotificationsRequest{PageSize: -1},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "ascending",
+			req:  &pb.ListNotificationsRequest{Order: "asc", PageSize: 10},
+			want: repository.NotificationFilter{Ascending: true, Limit: 10},
+		},
+		{
+			name:     "unknown order",
+			req:      &pb.ListNotificationsRequest{Order: "sideways"},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "created bounds",
+			req: &pb.ListNotificationsRequest{
+				CreatedAfter:  "2025-04-05T09:00:00Z",
+				CreatedBefore: "2025-04-05T12:00:00+02:00",
+			},
+			want: repository.NotificationFilter{
+				CreatedAfter:  created,
+				CreatedBefore: time.Date(2025, 4, 5, 10, 0, 0, 0, time.UTC),
+				Limit:         defaultListPageSize,
+			},
+		},
+		{
+			name:     "bad created_after",
+			req:      &pb.ListNotificationsRequest{CreatedAfter: "yesterday"},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "bad created_before",
+			req:      &pb.ListNotificationsRequest{CreatedBefore: "2025-04-05"},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "page token",
+			req:  &pb.ListNotificationsRequest{PageToken: descToken},
+			want: repository.NotificationFilter{
+				After: &repository.NotificationCursor{CreatedAt: created, ID: 10},
+				Limit: defaultListPageSize,
+			},
+		},
+		{
+			name:     "page token for the other order",
+			req:      &pb.ListNotificationsRequest{PageToken: ascToken},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "garbage page token",
+			req:      &pb.ListNotificationsRequest{PageToken: "garbage"},
+			wantCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := listFilter(tt.req)
+			if tt.wantCode != codes.OK {
+				assert.Equal(t, tt.wantCode, status.Code(err))
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want.UserID, got.UserID)
+			assert.Equal(t, tt.want.Status, got.Status)
+			assert.Equal(t, tt.want.Type, got.Type)
+			assert.True(t, tt.want.CreatedAfter.Equal(got.CreatedAfter), "created_after")
+			assert.True(t, tt.want.CreatedBefore.Equal(got.CreatedBefore), "created_before")
+			assert.Equal(t, tt.want.Ascending, got.Ascending)
+			assert.Equal(t, tt.want.Limit, got.Limit)
+			if tt.want.After == nil {
+				assert.Nil(t, got.After)
+			} else {
+				require.NotNil(t, got.After)
+				assert.True(t, tt.want.After.CreatedAt.Equal(got.After.CreatedAt))
+				assert.Equal(t, tt.want.After.ID, got.After.ID)
+			}
+		})
+	}
+}
+
+func TestListNotificationsPages(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	s := &NotificationServer{
+		db:  &repository.DB{Conn: sqlx.NewDb(mockDB, "postgres")},
+		log: zap.NewNop(),
+	}
+
+	columns := []string{"id", "user_id", "type", "category", "message", "status", "status_reason", "caller", "created_at", "read_at"}
+	first := time.Date(2025, 4, 5, 9, 2, 0, 0, time.UTC)
+	second := time.Date(2025, 4, 5, 9, 1, 0, 0, time.UTC)
+
+	// One more row than the page size is asked for to detect a next page
+	mock.ExpectQuery("FROM notifications").
+		WithArgs("default", "u1", "", "", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), 3).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(3, "u1", "email", nil, "c", "sent", nil, "api", first, nil).
+			AddRow(2, "u1", "email", nil, "b", "sent", nil, "api", second, nil).
+			AddRow(1, "u1", "email", nil, "a", "sent", nil, "api", second, nil))
+
+	resp, err := s.ListNotifications(context.Background(), &pb.ListNotificationsRequest{UserId: "u1", PageSize: 2})
+	require.NoError(t, err)
+	require.Len(t, resp.Notifications, 2)
+	assert.Equal(t, int64(3), resp.Notifications[0].Id)
+	assert.Equal(t, "2025-04-05T09:02:00Z", resp.Notifications[0].CreatedAt)
+
+	token, err := decodePageToken(resp.NextPageToken)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), token.ID)
+	assert.True(t, second.Equal(token.CreatedAt))
+	assert.False(t, token.Ascending)
+
+	// The last page has no token
+	mock.ExpectQuery("FROM notifications").
+		WithArgs("default", "u1", "", "", sqlmock.AnyArg(), sqlmock.AnyArg(), second, int64(2), 3).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, "u1", "email", nil, "a", "sent", nil, "api", second, nil))
+
+	resp, err = s.ListNotifications(context.Background(), &pb.ListNotificationsRequest{
+		UserId:    "u1",
+		PageSize:  2,
+		PageToken: resp.NextPageToken,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Notifications, 1)
+	assert.Empty(t, resp.NextPageToken)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
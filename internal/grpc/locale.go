@@ -0,0 +1,76 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	"go.uber.org/zap"
+
+	pb "github.com/officiallysidsingh/go-notify/api/generated"
+	"github.com/officiallysidsingh/go-notify/internal/locale"
+	"github.com/officiallysidsingh/go-notify/internal/logging"
+)
+
+// Returns the locale stored for a user
+func (s *NotificationServer) GetUserLocale(
+	ctx context.Context,
+	req *pb.GetUserLocaleRequest,
+) (
+	*pb.UserLocaleResponse,
+	error,
+) {
+	if req.UserId == "" {
+		return &pb.UserLocaleResponse{Error: "user_id is required"}, errors.New("user_id is required")
+	}
+
+	userLocale, err := s.db.GetUserLocale(ctx, req.UserId)
+	if err != nil {
+		s.logger(ctx).Error("Failed to get locale", logging.UserID(req.UserId), zap.Error(err))
+		return &pb.UserLocaleResponse{UserId: req.UserId, Error: err.Error()}, err
+	}
+
+	return &pb.UserLocaleResponse{
+		UserId: req.UserId,
+		Locale: userLocale,
+	}, nil
+}
+
+// Stores the preferred locale for a user
+func (s *NotificationServer) SetUserLocale(
+	ctx context.Context,
+	req *pb.SetUserLocaleRequest,
+) (
+	*pb.UserLocaleResponse,
+	error,
+) {
+	if req.UserId == "" {
+		return &pb.UserLocaleResponse{Error: "user_id is required"}, errors.New("user_id is required")
+	}
+
+	userLocale := locale.Normalize(req.Locale)
+	if err := s.db.SetUserLocale(ctx, req.UserId, userLocale); err != nil {
+		s.logger(ctx).Error("Failed to set locale", logging.UserID(req.UserId), zap.Error(err))
+		return &pb.UserLocaleResponse{UserId: req.UserId, Error: err.Error()}, err
+	}
+
+	return &pb.UserLocaleResponse{
+		UserId: req.UserId,
+		Locale: userLocale,
+	}, nil
+}
+
+// Converts the request variants into the queue payload format
+func toLocalizedContent(variants map[string]*pb.LocalizedContent) map[string]LocalizedContent {
+	if len(variants) == 0 {
+		return nil
+	}
+
+	localized := make(map[string]LocalizedContent, len(variants))
+	for tag, content := range variants {
+		localized[tag] = LocalizedContent{
+			Title:   content.GetTitle(),
+			Message: content.GetMessage(),
+		}
+	}
+	return localized
+}
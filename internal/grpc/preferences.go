@@ -0,0 +1,83 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	pb "github.com/officiallysidsingh/go-notify/api/generated"
+	"github.com/officiallysidsingh/go-notify/internal/logging"
+	"github.com/officiallysidsingh/go-notify/internal/repository"
+)
+
+// Returns a user's notification preferences
+func (s *NotificationServer) GetPreferences(
+	ctx context.Context,
+	req *pb.GetPreferencesRequest,
+) (
+	*pb.PreferencesResponse,
+	error,
+) {
+	if req.UserId == "" {
+		return &pb.PreferencesResponse{Error: "user_id is required"}, errors.New("user_id is required")
+	}
+
+	prefs, err := s.db.GetPreferences(ctx, req.UserId)
+	if err != nil {
+		s.logger(ctx).Error("Failed to get preferences", logging.UserID(req.UserId), zap.Error(err))
+		return &pb.PreferencesResponse{UserId: req.UserId, Error: err.Error()}, err
+	}
+
+	return &pb.PreferencesResponse{
+		UserId:      req.UserId,
+		Preferences: toPBPreferences(prefs),
+	}, nil
+}
+
+// Creates or updates a user's notification preferences
+func (s *NotificationServer) UpdatePreferences(
+	ctx context.Context,
+	req *pb.UpdatePreferencesRequest,
+) (
+	*pb.PreferencesResponse,
+	error,
+) {
+	if req.UserId == "" {
+		return &pb.PreferencesResponse{Error: "user_id is required"}, errors.New("user_id is required")
+	}
+
+	prefs := make([]repository.Preference, 0, len(req.Preferences))
+	for _, p := range req.Preferences {
+		if p.Channel == "" || p.Category == "" {
+			err := fmt.Errorf("channel and category are required (use %q to match any)", repository.PreferenceWildcard)
+			return &pb.PreferencesResponse{UserId: req.UserId, Error: err.Error()}, err
+		}
+		prefs = append(prefs, repository.Preference{
+			Channel:  p.Channel,
+			Category: p.Category,
+			Enabled:  p.Enabled,
+		})
+	}
+
+	if err := s.db.UpsertPreferences(ctx, req.UserId, prefs); err != nil {
+		s.logger(ctx).Error("Failed to update preferences", logging.UserID(req.UserId), zap.Error(err))
+		return &pb.PreferencesResponse{UserId: req.UserId, Error: err.Error()}, err
+	}
+
+	return s.GetPreferences(ctx, &pb.GetPreferencesRequest{UserId: req.UserId})
+}
+
+// Converts repository preferences into their protobuf form
+func toPBPreferences(prefs []repository.Preference) []*pb.Preference {
+	result := make([]*pb.Preference, 0, len(prefs))
+	for _, p := range prefs {
+		result = append(result, &pb.Preference{
+			Channel:  p.Channel,
+			Category: p.Category,
+			Enabled:  p.Enabled,
+		})
+	}
+	return result
+}
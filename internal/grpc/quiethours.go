@@ -0,0 +1,82 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+
+	pb "github.com/officiallysidsingh/go-notify/api/generated"
+	"github.com/officiallysidsingh/go-notify/internal/logging"
+	"github.com/officiallysidsingh/go-notify/internal/quiethours"
+	"github.com/officiallysidsingh/go-notify/internal/repository"
+)
+
+// Returns a user's timezone and quiet hours
+func (s *NotificationServer) GetQuietHours(
+	ctx context.Context,
+	req *pb.GetQuietHoursRequest,
+) (
+	*pb.QuietHoursResponse,
+	error,
+) {
+	if req.UserId == "" {
+		return &pb.QuietHoursResponse{Error: "user_id is required"}, errors.New("user_id is required")
+	}
+
+	quiet, err := s.db.GetQuietHours(ctx, req.UserId)
+	if err != nil {
+		s.logger(ctx).Error("Failed to get quiet hours", logging.UserID(req.UserId), zap.Error(err))
+		return &pb.QuietHoursResponse{UserId: req.UserId, Error: err.Error()}, err
+	}
+
+	return toPBQuietHours(req.UserId, quiet), nil
+}
+
+// Sets a user's timezone and quiet hours
+func (s *NotificationServer) SetQuietHours(
+	ctx context.Context,
+	req *pb.SetQuietHoursRequest,
+) (
+	*pb.QuietHoursResponse,
+	error,
+) {
+	if req.UserId == "" {
+		return &pb.QuietHoursResponse{Error: "user_id is required"}, errors.New("user_id is required")
+	}
+
+	quiet := repository.QuietHours{Timezone: req.Timezone}
+	if quiet.Timezone == "" {
+		quiet.Timezone = "UTC"
+	}
+
+	if _, err := time.LoadLocation(quiet.Timezone); err != nil {
+		return &pb.QuietHoursResponse{UserId: req.UserId, Error: "invalid timezone"}, err
+	}
+
+	if req.Enabled {
+		quiet.Start, quiet.End = req.Start, req.End
+		if _, err := quiethours.Parse(quiet.Start, quiet.End, quiet.Timezone); err != nil {
+			return &pb.QuietHoursResponse{UserId: req.UserId, Error: err.Error()}, err
+		}
+	}
+
+	if err := s.db.SetQuietHours(ctx, req.UserId, quiet); err != nil {
+		s.logger(ctx).Error("Failed to set quiet hours", logging.UserID(req.UserId), zap.Error(err))
+		return &pb.QuietHoursResponse{UserId: req.UserId, Error: err.Error()}, err
+	}
+
+	return toPBQuietHours(req.UserId, quiet), nil
+}
+
+// Converts repository quiet hours into their protobuf form
+func toPBQuietHours(userID string, quiet repository.QuietHours) *pb.QuietHoursResponse {
+	return &pb.QuietHoursResponse{
+		UserId:   userID,
+		Timezone: quiet.Timezone,
+		Enabled:  quiet.Start != "" && quiet.End != "",
+		Start:    quiet.Start,
+		End:      quiet.End,
+	}
+}
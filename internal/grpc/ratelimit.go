@@ -0,0 +1,80 @@
+package grpc
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/officiallysidsingh/go-notify/internal/ratelimiter"
+)
+
+// Response metadata keys describing the caller's rate limit
+const (
+	rateLimitLimitHeader     = "x-ratelimit-limit"
+	rateLimitRemainingHeader = "x-ratelimit-remaining"
+	rateLimitResetHeader     = "x-ratelimit-reset" // Unix seconds
+	retryAfterHeader         = "retry-after"       // Seconds
+)
+
+// Sends the rate limit state as response metadata
+func (s *NotificationServer) setRateLimitHeaders(ctx context.Context, result ratelimiter.Result) {
+	// Exempt requests have no limit to report
+	if result.Limit < 0 {
+		return
+	}
+
+	md := metadata.Pairs(
+		rateLimitLimitHeader, strconv.Itoa(result.Limit),
+		rateLimitRemainingHeader, strconv.Itoa(result.Remaining),
+		rateLimitResetHeader, strconv.FormatInt(result.ResetAt.Unix(), 10),
+	)
+	if !result.Allowed {
+		md.Set(retryAfterHeader, strconv.Itoa(retryAfterSeconds(result.RetryAfter)))
+	}
+
+	if err := grpc.SetHeader(ctx, md); err != nil {
+		s.logger(ctx).Warn("Failed to set rate limit headers", zap.Error(err))
+	}
+
+	// Rejections also carry them in the trailers, next to the error status
+	if !result.Allowed {
+		if err := grpc.SetTrailer(ctx, md); err != nil {
+			s.logger(ctx).Warn("Failed to set rate limit trailers", zap.Error(err))
+		}
+	}
+}
+
+// Builds a ResourceExhausted error carrying RetryInfo and QuotaFailure details
+func rateLimitExceededError(result ratelimiter.Result) error {
+	st := status.New(codes.ResourceExhausted, "rate limit exceeded")
+
+	detailed, err := st.WithDetails(
+		&errdetails.RetryInfo{
+			RetryDelay: durationpb.New(result.RetryAfter),
+		},
+		&errdetails.QuotaFailure{
+			Violations: []*errdetails.QuotaFailure_Violation{{
+				Subject:     "user",
+				Description: "notification rate limit of " + strconv.Itoa(result.Limit) + " exceeded",
+			}},
+		},
+	)
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// Rounds a retry delay up to whole seconds, as used by Retry-After
+func retryAfterSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -2,17 +2,25 @@ package grpc
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
-	"fmt"
-	"log"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	pb "github.com/officiallysidsingh/go-notify/api/generated"
+	"github.com/officiallysidsingh/go-notify/internal/dedup"
+	"github.com/officiallysidsingh/go-notify/internal/logging"
 	"github.com/officiallysidsingh/go-notify/internal/producer"
+	"github.com/officiallysidsingh/go-notify/internal/quota"
 	"github.com/officiallysidsingh/go-notify/internal/ratelimiter"
 	"github.com/officiallysidsingh/go-notify/internal/repository"
+	"github.com/officiallysidsingh/go-notify/internal/statuswatch"
+	"github.com/officiallysidsingh/go-notify/internal/tenant"
 )
 
 // NotificationMessage defines the payload published to RabbitMQ.
@@ -23,13 +31,25 @@ type NotificationMessage struct {
 	Priority       string `json:"priority"`
 	Message        string `json:"message"`
 	Type           string `json:"type"`
+	TenantID       string `json:"tenant_id,omitempty"`
+	Category       string `json:"category,omitempty"`
+	DigestKey      string `json:"digest_key,omitempty"`
+	CallbackURL    string `json:"callback_url,omitempty"`
+
+	Localized map[string]LocalizedContent `json:"localized,omitempty"`
+}
+
+// LocalizedContent is a per-locale variant of the title and message.
+type LocalizedContent struct {
+	Title   string `json:"title"`
+	Message string `json:"message"`
 }
 
 // Prometheus total notification counter
 var notificationsReceived = prometheus.NewCounter(
 	prometheus.CounterOpts{
 		Name: "notifications_received_total",
-		Help: "Total number of notifications received via gRPC",
+		Help: "Total number of notification requests received, including rejected ones",
 	},
 )
 
@@ -38,11 +58,25 @@ type NotificationServer struct {
 	producer    *producer.RabbitMQProducer
 	db          *repository.DB
 	rateLimiter *ratelimiter.RateLimiter
+	dedup       *dedup.Deduplicator
+	quotas      quota.Quotas
+	tenants     *tenant.Registry
+	statusHub   *statuswatch.Hub
+	log         *zap.Logger
 }
 
-// Init prometheus counter
+// Prometheus deduplicated notification counter
+var notificationsDeduplicated = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "notifications_deduplicated_total",
+		Help: "Total number of duplicate notifications dropped",
+	},
+)
+
+// Init prometheus counters
 func init() {
 	prometheus.MustRegister(notificationsReceived)
+	prometheus.MustRegister(notificationsDeduplicated)
 }
 
 // Init gRPC server
@@ -50,14 +84,29 @@ func NewNotificationServer(
 	producer *producer.RabbitMQProducer,
 	db *repository.DB,
 	limiter *ratelimiter.RateLimiter,
+	deduplicator *dedup.Deduplicator,
+	quotas quota.Quotas,
+	tenants *tenant.Registry,
+	statusHub *statuswatch.Hub,
+	logger *zap.Logger,
 ) *NotificationServer {
 	return &NotificationServer{
 		producer:    producer,
 		db:          db,
 		rateLimiter: limiter,
+		dedup:       deduplicator,
+		quotas:      quotas,
+		tenants:     tenants,
+		statusHub:   statusHub,
+		log:         logger,
 	}
 }
 
+// Returns the server's logger with the request's trace ID
+func (s *NotificationServer) logger(ctx context.Context) *zap.Logger {
+	return logging.FromContext(ctx, s.log)
+}
+
 // To handle notification requests
 func (s *NotificationServer) SendNotification(
 	ctx context.Context,
@@ -66,10 +115,60 @@ func (s *NotificationServer) SendNotification(
 	*pb.NotificationResponse,
 	error,
 ) {
+	return s.sendNotification(ctx, req, true)
+}
+
+// Accepts one notification. Rate limit headers are only sent when
+// reportLimits is set, since a batch shares one set of response headers.
+func (s *NotificationServer) sendNotification(
+	ctx context.Context,
+	req *pb.NotificationRequest,
+	reportLimits bool,
+) (
+	*pb.NotificationResponse,
+	error,
+) {
+	// Count every request, including the ones rejected below
+	notificationsReceived.Inc()
+	labels := notificationLabels(req.Type, req.Priority)
+
+	// Scope the request to its tenant and render the tenant's template
+	t, err := s.requestTenant(ctx, req)
+	if err != nil {
+		return &pb.NotificationResponse{Success: false, Error: err.Error()}, err
+	}
+	ctx = tenant.NewContext(ctx, t.ID)
+	if err := renderTemplate(t, req); err != nil {
+		return &pb.NotificationResponse{Success: false, Error: err.Error()}, err
+	}
+	if err := validateCallback(t, req.CallbackUrl); err != nil {
+		return &pb.NotificationResponse{Success: false, Error: err.Error()}, err
+	}
+
+	// Drop repeats of a notification accepted within the dedup window
+	dedupKey, duplicate := s.claimDedupKey(ctx, req)
+	if duplicate != nil {
+		return duplicate, nil
+	}
+
+	// Free the key on failure so that the caller's retry is not dropped
+	published := false
+	defer func() {
+		if !published {
+			s.releaseDedupKey(dedupKey)
+		}
+	}()
+
 	// Rate limiting
-	allowed, err := s.rateLimiter.Allow(ctx, req.UserId)
+	limit, err := s.rateLimiter.Allow(ctx, ratelimiter.Request{
+		Tenant:   t.ID,
+		UserID:   req.UserId,
+		Channel:  req.Type,
+		Priority: req.Priority,
+		Category: req.Category,
+	})
 	if err != nil {
-		log.Printf("Rate limiter error: %v", err)
+		s.logger(ctx).Error("Rate limiter error", logging.UserID(req.UserId), logging.Channel(req.Type), zap.Error(err))
 		return &pb.NotificationResponse{
 				Success: false,
 				Error:   "Rate limiter error",
@@ -77,23 +176,56 @@ func (s *NotificationServer) SendNotification(
 				"rate limiter error",
 			)
 	}
-	if !allowed {
+
+	// Tell the caller its quota, and when to retry if it ran out
+	if reportLimits {
+		s.setRateLimitHeaders(ctx, limit)
+	}
+	if !limit.Allowed {
+		notificationsRateLimited.WithLabelValues(labels...).Inc()
 		return &pb.NotificationResponse{
-				Success: false,
-				Error:   "Rate limit exceeded",
-			}, errors.New(
-				"rate limit exceeded",
-			)
+			Success: false,
+			Error:   "Rate limit exceeded",
+		}, rateLimitExceededError(limit)
 	}
 
-	notificationsReceived.Inc()
-	log.Printf("Received notification request for user: %s", req.UserId)
+	// Enforce the caller's daily and monthly quota for the channel, giving it
+	// back if the notification isn't queued
+	caller := callerFromContext(ctx)
+	refundQuota, err := s.consumeQuota(ctx, caller, req.Type)
+	if err != nil {
+		return &pb.NotificationResponse{Success: false, Error: err.Error()}, err
+	}
+	defer func() {
+		if !published {
+			refundQuota()
+		}
+	}()
+
+	logger := s.logger(ctx).With(logging.UserID(req.UserId), logging.Channel(req.Type))
+	logger.Info("Received notification request")
 
 	// Insert notification into db
-	notificationID, err := s.db.InsertNotification(ctx, req.UserId, req.Message, "pending")
+	insertStart := time.Now()
+	notificationID, err := s.db.InsertNotification(ctx, repository.NewNotification{
+		UserID:   req.UserId,
+		Caller:   caller,
+		Type:     req.Type,
+		Category: req.Category,
+		Message:  req.Message,
+		Status:   "pending",
+
+		CallbackURL: req.CallbackUrl,
+	})
+	notificationsInsertDuration.WithLabelValues(labels...).Observe(time.Since(insertStart).Seconds())
 	if err != nil {
 		return &pb.NotificationResponse{Success: false, Error: err.Error()}, err
 	}
+	s.confirmDedupKey(ctx, dedupKey, notificationID)
+
+	// Later log lines of the request, including the producer's, name the notification
+	logger = logger.With(logging.NotificationID(notificationID))
+	ctx = logging.NewContext(ctx, logger)
 
 	// Prepare payload
 	payload := NotificationMessage{
@@ -103,6 +235,11 @@ func (s *NotificationServer) SendNotification(
 		Priority:       req.Priority,
 		Message:        req.Message,
 		Type:           req.Type,
+		TenantID:       t.ID,
+		Category:       req.Category,
+		DigestKey:      req.DigestKey,
+		CallbackURL:    req.CallbackUrl,
+		Localized:      toLocalizedContent(req.Localized),
 	}
 	data, err := json.Marshal(payload)
 	if err != nil {
@@ -110,12 +247,15 @@ func (s *NotificationServer) SendNotification(
 	}
 
 	// Publish the payload to RabbitMQ
-	err = s.producer.Publish("notification_exchange_topic", req.Type, string(data))
+	err = s.producer.Publish(ctx, "notification_exchange_topic", req.Type, string(data))
 	if err != nil {
+		notificationsPublishFailures.WithLabelValues(labels...).Inc()
 		return &pb.NotificationResponse{Success: false, Error: err.Error()}, err
 	}
+	published = true
+	logger.Info("Notification queued")
 
-	return &pb.NotificationResponse{Success: true}, nil
+	return &pb.NotificationResponse{Success: true, NotificationId: notificationID}, nil
 }
 
 func (s *NotificationServer) GetNotificationStatus(
@@ -125,18 +265,20 @@ func (s *NotificationServer) GetNotificationStatus(
 	*pb.StatusResponse,
 	error,
 ) {
-	var status string
-	query := "SELECT status FROM notifications WHERE id=$1"
-
-	err := s.db.Conn.Get(&status, query, req.NotificationId)
+	current, err := s.db.GetNotificationStatus(ctx, int64(req.NotificationId))
+	if errors.Is(err, sql.ErrNoRows) {
+		err = status.Errorf(codes.NotFound, "notification %d not found", req.NotificationId)
+		return &pb.StatusResponse{Error: err.Error()}, err
+	}
 	if err != nil {
-		return &pb.StatusResponse{
-			Status: "",
-			Error:  fmt.Sprintf("Notification not found: %v", err.Error()),
-		}, err
+		s.logger(ctx).Error("Failed to get notification status", logging.NotificationID(int64(req.NotificationId)), zap.Error(err))
+		err = status.Error(codes.Internal, "failed to get notification status")
+		return &pb.StatusResponse{Error: err.Error()}, err
 	}
 
 	return &pb.StatusResponse{
-		Status: status,
+		Status:       current.Status,
+		StatusReason: current.Reason,
+		ReadAt:       formatNullTime(current.ReadAt),
 	}, nil
 }
@@ -0,0 +1,57 @@
+package grpc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/officiallysidsingh/go-notify/api/generated"
+	"github.com/officiallysidsingh/go-notify/internal/repository"
+)
+
+func TestGetNotificationStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		queryErr   error
+		wantCode   codes.Code
+		wantStatus string
+	}{
+		{name: "found", wantCode: codes.OK, wantStatus: "sent"},
+		{name: "unknown id", queryErr: sql.ErrNoRows, wantCode: codes.NotFound},
+		{name: "database error", queryErr: errors.New("connection reset"), wantCode: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer mockDB.Close()
+
+			s := &NotificationServer{
+				db:  &repository.DB{Conn: sqlx.NewDb(mockDB, "postgres")},
+				log: zap.NewNop(),
+			}
+
+			query := mock.ExpectQuery("SELECT status, status_reason, read_at FROM notifications").WithArgs(7, "default")
+			if tt.queryErr != nil {
+				query.WillReturnError(tt.queryErr)
+			} else {
+				query.WillReturnRows(sqlmock.NewRows([]string{"status", "status_reason", "read_at"}).AddRow("sent", nil, nil))
+			}
+
+			resp, err := s.GetNotificationStatus(context.Background(), &pb.StatusRequest{NotificationId: 7})
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			assert.Equal(t, tt.wantStatus, resp.Status)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
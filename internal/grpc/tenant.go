@@ -0,0 +1,110 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/officiallysidsingh/go-notify/api/generated"
+	"github.com/officiallysidsingh/go-notify/internal/auth"
+	"github.com/officiallysidsingh/go-notify/internal/tenant"
+)
+
+// Returns the tenant named in the request metadata, or ""
+func tenantFromMetadata(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(tenant.MetadataKey); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Returns the requested tenant, defaulting to the one the client is bound to.
+// Rejects unknown tenants and tenants the client may not access.
+func resolveTenant(ctx context.Context, tenants *tenant.Registry, id string) (*tenant.Tenant, error) {
+	principal, authenticated := auth.FromContext(ctx)
+	if id == "" && authenticated {
+		id = principal.Tenant
+	}
+
+	t, err := tenants.Get(id)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if authenticated && !principal.CanAccessTenant(t.ID) {
+		return nil, status.Errorf(codes.PermissionDenied, "not allowed to access tenant %s", t.ID)
+	}
+	return t, nil
+}
+
+// Scopes every call to the tenant named in its metadata, rejecting unknown tenants
+func TenantUnaryInterceptor(tenants *tenant.Registry) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		t, err := resolveTenant(ctx, tenants, tenantFromMetadata(ctx))
+		if err != nil {
+			return nil, err
+		}
+		return handler(tenant.NewContext(ctx, t.ID), req)
+	}
+}
+
+// Scopes every stream to the tenant named in its metadata, rejecting unknown tenants
+func TenantStreamInterceptor(tenants *tenant.Registry) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		ctx := ss.Context()
+		t, err := resolveTenant(ctx, tenants, tenantFromMetadata(ctx))
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: tenant.NewContext(ctx, t.ID)})
+	}
+}
+
+// Resolves the tenant of a notification request, which may name it in the
+// request body as well as in metadata
+func (s *NotificationServer) requestTenant(ctx context.Context, req *pb.NotificationRequest) (*tenant.Tenant, error) {
+	id := tenantFromMetadata(ctx)
+	if req.TenantId != "" {
+		if id != "" && id != req.TenantId {
+			return nil, status.Error(codes.InvalidArgument, "tenant_id does not match "+tenant.MetadataKey)
+		}
+		id = req.TenantId
+	}
+
+	return resolveTenant(ctx, s.tenants, id)
+}
+
+// Fills in the title and message from the tenant's template, if one is named
+func renderTemplate(t *tenant.Tenant, req *pb.NotificationRequest) error {
+	if req.Template == "" {
+		return nil
+	}
+
+	title, message, err := t.Render(req.Template, req.TemplateData)
+	if errors.Is(err, tenant.ErrUnknownTemplate) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return err
+	}
+
+	req.Title, req.Message = title, message
+	return nil
+}
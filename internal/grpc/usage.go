@@ -0,0 +1,126 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/officiallysidsingh/go-notify/api/generated"
+	"github.com/officiallysidsingh/go-notify/internal/auth"
+	"github.com/officiallysidsingh/go-notify/internal/logging"
+)
+
+// Request metadata key identifying the calling service
+const callerMetadataKey = "x-client-id"
+
+// Recorded for requests without a caller
+const anonymousCaller = "anonymous"
+
+// Returns the authenticated client, or the calling service named in the
+// request metadata when authentication is off
+func callerFromContext(ctx context.Context) string {
+	if principal, ok := auth.FromContext(ctx); ok && principal.Subject != "" {
+		return principal.Subject
+	}
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return anonymousCaller
+	}
+	if values := md.Get(callerMetadataKey); len(values) > 0 && values[0] != "" {
+		return values[0]
+	}
+	return anonymousCaller
+}
+
+// Counts the request against the caller's quota for the channel and returns
+// a func that takes it back, for requests that fail before being queued.
+// Returns a ResourceExhausted error once the quota is used up.
+func (s *NotificationServer) consumeQuota(ctx context.Context, caller, channel string) (func(), error) {
+	noRefund := func() {}
+	q, ok := s.quotas.Lookup(caller, channel)
+	if !ok {
+		return noRefund, nil
+	}
+
+	now := time.Now()
+	usage, err := s.db.ConsumeQuota(ctx, caller, channel, now, q.Daily, q.Monthly)
+	if err != nil {
+		s.logger(ctx).Error("Quota check failed", zap.String("caller", caller), logging.Channel(channel), zap.Error(err))
+		return noRefund, errors.New("quota check error")
+	}
+	if usage.Allowed {
+		refund := func() {
+			// The request may have failed because its context ended
+			ctx := context.WithoutCancel(ctx)
+			if err := s.db.RefundQuota(ctx, caller, channel, now); err != nil {
+				s.logger(ctx).Error("Failed to refund quota", zap.String("caller", caller), logging.Channel(channel), zap.Error(err))
+			}
+		}
+		return refund, nil
+	}
+
+	var violations []*errdetails.QuotaFailure_Violation
+	if q.Daily > 0 && usage.Daily >= q.Daily {
+		violations = append(violations, &errdetails.QuotaFailure_Violation{
+			Subject:     "caller:" + caller,
+			Description: fmt.Sprintf("daily %s quota of %d exceeded", channel, q.Daily),
+		})
+	}
+	if q.Monthly > 0 && usage.Monthly >= q.Monthly {
+		violations = append(violations, &errdetails.QuotaFailure_Violation{
+			Subject:     "caller:" + caller,
+			Description: fmt.Sprintf("monthly %s quota of %d exceeded", channel, q.Monthly),
+		})
+	}
+
+	st := status.New(codes.ResourceExhausted, "quota exceeded")
+	if detailed, err := st.WithDetails(&errdetails.QuotaFailure{Violations: violations}); err == nil {
+		return noRefund, detailed.Err()
+	}
+	return noRefund, st.Err()
+}
+
+// Returns notification counts by caller, channel and status over a time range
+func (s *NotificationServer) GetUsage(
+	ctx context.Context,
+	req *pb.GetUsageRequest,
+) (
+	*pb.UsageResponse,
+	error,
+) {
+	from, err := time.Parse(time.RFC3339, req.From)
+	if err != nil {
+		return &pb.UsageResponse{Error: "from must be an RFC 3339 timestamp"}, err
+	}
+	to := time.Now()
+	if req.To != "" {
+		if to, err = time.Parse(time.RFC3339, req.To); err != nil {
+			return &pb.UsageResponse{Error: "to must be an RFC 3339 timestamp"}, err
+		}
+	}
+
+	records, err := s.db.GetUsage(ctx, req.Caller, from, to)
+	if err != nil {
+		s.logger(ctx).Error("Failed to get usage", zap.String("caller", req.Caller), zap.Error(err))
+		return &pb.UsageResponse{Error: err.Error()}, err
+	}
+
+	resp := &pb.UsageResponse{Records: make([]*pb.UsageRecord, 0, len(records))}
+	for _, r := range records {
+		resp.Records = append(resp.Records, &pb.UsageRecord{
+			Caller:  r.Caller,
+			Channel: r.Channel,
+			Status:  r.Status,
+			Count:   r.Count,
+		})
+	}
+	return resp, nil
+}
@@ -0,0 +1,97 @@
+package grpc
+
+import (
+	"database/sql"
+	"errors"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/officiallysidsingh/go-notify/api/generated"
+	"github.com/officiallysidsingh/go-notify/internal/logging"
+	"github.com/officiallysidsingh/go-notify/internal/tenant"
+)
+
+// Statuses after which a notification doesn't change again.
+// Deliveries the worker will retry are "retrying" instead of "failed".
+var finalStatuses = map[string]bool{
+	"sent":       true,
+	"suppressed": true,
+	"digested":   true,
+	"cancelled":  true,
+	"failed":     true,
+}
+
+// Streams a notification's status changes until it reaches a final status
+func (s *NotificationServer) WatchNotificationStatus(
+	req *pb.WatchNotificationStatusRequest,
+	stream grpc.ServerStreamingServer[pb.NotificationStatusUpdate],
+) error {
+	if s.statusHub == nil {
+		return status.Error(codes.Unimplemented, "status streaming is disabled")
+	}
+	ctx := stream.Context()
+
+	// Watch before reading the current status so no change is missed in between
+	updates, stop := s.statusHub.Watch(req.NotificationId)
+	defer stop()
+
+	current, err := s.sendCurrentStatus(stream, req.NotificationId)
+	if err != nil || finalStatuses[current] {
+		return err
+	}
+
+	tenantID := tenant.FromContext(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case update := <-updates:
+			if update.Resync {
+				current, err = s.sendCurrentStatus(stream, req.NotificationId)
+				if err != nil || finalStatuses[current] {
+					return err
+				}
+				continue
+			}
+			if update.TenantID != tenantID || update.Status == current {
+				continue
+			}
+
+			current = update.Status
+			err := stream.Send(&pb.NotificationStatusUpdate{
+				NotificationId: update.ID,
+				Status:         update.Status,
+				StatusReason:   update.StatusReason,
+				Final:          finalStatuses[update.Status],
+			})
+			if err != nil || finalStatuses[current] {
+				return err
+			}
+		}
+	}
+}
+
+// Reads the notification's status and sends it, returning the status sent
+func (s *NotificationServer) sendCurrentStatus(
+	stream grpc.ServerStreamingServer[pb.NotificationStatusUpdate],
+	id int64,
+) (string, error) {
+	current, err := s.db.GetNotificationStatus(stream.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", status.Errorf(codes.NotFound, "notification %d not found", id)
+	}
+	if err != nil {
+		s.logger(stream.Context()).Error("Failed to watch notification", logging.NotificationID(id), zap.Error(err))
+		return "", status.Error(codes.Internal, "failed to get notification status")
+	}
+
+	return current.Status, stream.Send(&pb.NotificationStatusUpdate{
+		NotificationId: id,
+		Status:         current.Status,
+		StatusReason:   current.Reason,
+		Final:          finalStatuses[current.Status],
+	})
+}
@@ -0,0 +1,67 @@
+package locale
+
+import (
+	"strings"
+)
+
+// Recorded on a notification when no localized variant matched
+const Default = "default"
+
+// Converts a locale tag into its canonical form (e.g. "pt_br" -> "pt-BR")
+func Normalize(tag string) string {
+	parts := strings.Split(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"), "-")
+	for i, part := range parts {
+		switch {
+		case i == 0:
+			parts[i] = strings.ToLower(part)
+		case len(part) == 2:
+			parts[i] = strings.ToUpper(part)
+		case len(part) == 4:
+			parts[i] = strings.ToUpper(part[:1]) + strings.ToLower(part[1:])
+		default:
+			parts[i] = strings.ToLower(part)
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// Returns the tag followed by its less specific parents
+// (e.g. "zh-Hant-TW" -> "zh-Hant-TW", "zh-Hant", "zh")
+func Fallbacks(tag string) []string {
+	tag = Normalize(tag)
+	if tag == "" {
+		return nil
+	}
+
+	var chain []string
+	for {
+		chain = append(chain, tag)
+		idx := strings.LastIndex(tag, "-")
+		if idx <= 0 {
+			return chain
+		}
+		tag = tag[:idx]
+	}
+}
+
+// Picks the key of the best variant for the preferred locale.
+// Returns false when only the default content applies.
+func Match[T any](preferred string, variants map[string]T) (string, bool) {
+	if len(variants) == 0 {
+		return "", false
+	}
+
+	// Index variants by their canonical tag
+	byTag := make(map[string]string, len(variants))
+	for key := range variants {
+		byTag[strings.ToLower(Normalize(key))] = key
+	}
+
+	for _, candidate := range Fallbacks(preferred) {
+		if key, ok := byTag[strings.ToLower(candidate)]; ok {
+			return key, true
+		}
+	}
+
+	return "", false
+}
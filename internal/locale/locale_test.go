@@ -0,0 +1,65 @@
+package locale
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"en":         "en",
+		"EN":         "en",
+		"pt_br":      "pt-BR",
+		" en-gb ":    "en-GB",
+		"zh-hant-tw": "zh-Hant-TW",
+		"es-419":     "es-419",
+		"SR_LATN_rs": "sr-Latn-RS",
+		"":           "",
+	}
+
+	for tag, want := range tests {
+		assert.Equal(t, want, Normalize(tag), "tag %q", tag)
+	}
+}
+
+func TestFallbacks(t *testing.T) {
+	assert.Equal(t, []string{"zh-Hant-TW", "zh-Hant", "zh"}, Fallbacks("zh_hant_tw"))
+	assert.Equal(t, []string{"en"}, Fallbacks("en"))
+	assert.Nil(t, Fallbacks(""))
+}
+
+func TestMatch(t *testing.T) {
+	variants := map[string]string{
+		"en":      "Hello",
+		"en-GB":   "Hello, mate",
+		"pt_BR":   "Olá",
+		"zh-hant": "你好",
+	}
+
+	tests := []struct {
+		name      string
+		preferred string
+		variants  map[string]string
+		want      string
+		matched   bool
+	}{
+		{name: "exact", preferred: "en-GB", variants: variants, want: "en-GB", matched: true},
+		{name: "case and separator", preferred: "EN_gb", variants: variants, want: "en-GB", matched: true},
+		{name: "falls back to language", preferred: "en-US", variants: variants, want: "en", matched: true},
+		{name: "returns the variant's own key", preferred: "pt-BR", variants: variants, want: "pt_BR", matched: true},
+		{name: "falls back to script", preferred: "zh-Hant-HK", variants: variants, want: "zh-hant", matched: true},
+		{name: "no parent match", preferred: "pt-PT", variants: variants},
+		{name: "unknown language", preferred: "fr-FR", variants: variants},
+		{name: "no preference", preferred: "", variants: variants},
+		{name: "no variants", preferred: "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, matched := Match(tt.preferred, tt.variants)
+			assert.Equal(t, tt.matched, matched)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/officiallysidsingh/go-notify/internal/tracing"
+)
+
+// Builds the JSON production logger at the given level (debug, info, warn,
+// error), info when empty
+func New(level string) (*zap.Logger, error) {
+	cfg := zap.NewProductionConfig()
+	if level != "" {
+		parsed, err := zapcore.ParseLevel(level)
+		if err != nil {
+			return nil, fmt.Errorf("invalid logging level %q: %w", level, err)
+		}
+		cfg.Level = zap.NewAtomicLevelAt(parsed)
+	}
+
+	logger, err := cfg.Build()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build logger: %w", err)
+	}
+	return logger, nil
+}
+
+type contextKey struct{}
+
+// Returns a copy of ctx carrying a logger with the fields of the work it is for
+func NewContext(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// Returns the logger stored in ctx, or base with the context's trace ID
+func FromContext(ctx context.Context, base *zap.Logger) *zap.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*zap.Logger); ok {
+		return logger
+	}
+	return WithTrace(ctx, base)
+}
+
+// Adds the context's trace ID, so log lines can be found from a trace
+func WithTrace(ctx context.Context, logger *zap.Logger) *zap.Logger {
+	if traceID := tracing.TraceID(ctx); traceID != "" {
+		return logger.With(TraceID(traceID))
+	}
+	return logger
+}
+
+// Correlation fields shared by every service, so one notification can be
+// followed across their logs
+
+func NotificationID(id int64) zap.Field {
+	return zap.Int64("notification_id", id)
+}
+
+func UserID(id string) zap.Field {
+	return zap.String("user_id", id)
+}
+
+func Channel(channel string) zap.Field {
+	return zap.String("channel", channel)
+}
+
+func TraceID(id string) zap.Field {
+	return zap.String("trace_id", id)
+}
@@ -1,20 +1,28 @@
 package producer
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/streadway/amqp"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/officiallysidsingh/go-notify/internal/logging"
+	"github.com/officiallysidsingh/go-notify/internal/tracing"
 )
 
 type RabbitMQProducer struct {
 	conn    *amqp.Connection
 	channel *amqp.Channel
+	log     *zap.Logger
 }
 
 // Init RabbitMQ Producer
-func NewProducer(url string) (*RabbitMQProducer, error) {
+func NewProducer(url string, logger *zap.Logger) (*RabbitMQProducer, error) {
 	var conn *amqp.Connection
 	var err error
 
@@ -24,7 +32,7 @@ func NewProducer(url string) (*RabbitMQProducer, error) {
 		if err == nil {
 			break
 		}
-		log.Printf("RabbitMQ connection failed. Retrying... (%d/5)", i+1)
+		logger.Warn("RabbitMQ connection failed, retrying", zap.Int("attempt", i+1), zap.Error(err))
 		time.Sleep(2 * time.Second)
 	}
 	if err != nil {
@@ -39,6 +47,7 @@ func NewProducer(url string) (*RabbitMQProducer, error) {
 	producer := &RabbitMQProducer{
 		conn:    conn,
 		channel: ch,
+		log:     logger,
 	}
 
 	// Ensure exchanges and queues are created
@@ -129,6 +138,8 @@ func (p *RabbitMQProducer) SetupExchangesAndQueues() error {
 		{"queue_email", "email"},
 		{"queue_sms", "sms"},
 		{"queue_push", "push"},
+		{"queue_inapp", "inapp"},
+		{"queue_realtime", "realtime"},
 	}
 
 	for _, q := range queues {
@@ -171,8 +182,22 @@ func (p *RabbitMQProducer) SetupExchangesAndQueues() error {
 	return nil
 }
 
-// To send a message to the queue
-func (p *RabbitMQProducer) Publish(exchange, routingKey, message string) error {
+// To send a message to the queue, carrying the context's trace in its headers
+func (p *RabbitMQProducer) Publish(ctx context.Context, exchange, routingKey, message string) error {
+	ctx, span := tracing.Tracer().Start(
+		ctx,
+		exchange+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemRabbitmq,
+			semconv.MessagingOperationTypePublish,
+			semconv.MessagingDestinationName(exchange),
+			semconv.MessagingRabbitmqDestinationRoutingKey(routingKey),
+		),
+	)
+	defer span.End()
+
+	headers := tracing.InjectAMQP(ctx, nil)
 	var err error
 
 	// Retry upto 3 times
@@ -183,6 +208,7 @@ func (p *RabbitMQProducer) Publish(exchange, routingKey, message string) error {
 			false,
 			false,
 			amqp.Publishing{
+				Headers:      headers,
 				ContentType:  "application/json",
 				Body:         []byte(message),
 				DeliveryMode: amqp.Persistent,
@@ -192,12 +218,20 @@ func (p *RabbitMQProducer) Publish(exchange, routingKey, message string) error {
 			return nil
 		}
 
-		log.Printf("Failed to publish message. Retrying... (%d/3)", i+1)
+		logging.FromContext(ctx, p.log).Warn(
+			"Failed to publish message, retrying",
+			zap.String("exchange", exchange),
+			zap.String("routing_key", routingKey),
+			zap.Int("attempt", i+1),
+			zap.Error(err),
+		)
 
 		// Wait before retrying
 		time.Sleep(1 * time.Second)
 	}
 
+	span.RecordError(err)
+	span.SetStatus(otelcodes.Error, "publish failed")
 	return fmt.Errorf("failed to publish message after retries: %w", err)
 }
 
@@ -205,12 +239,12 @@ func (p *RabbitMQProducer) Publish(exchange, routingKey, message string) error {
 func (p *RabbitMQProducer) Close() {
 	if p.channel != nil {
 		if err := p.channel.Close(); err != nil {
-			log.Printf("error closing producer channel: %v", err)
+			p.log.Error("error closing producer channel", zap.Error(err))
 		}
 	}
 	if p.conn != nil {
 		if err := p.conn.Close(); err != nil {
-			log.Printf("error closing producer connection: %v", err)
+			p.log.Error("error closing producer connection", zap.Error(err))
 		}
 	}
 }
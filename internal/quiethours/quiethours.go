@@ -0,0 +1,75 @@
+package quiethours
+
+import (
+	"fmt"
+	"time"
+)
+
+// Window is a daily do-not-disturb period in a user's timezone.
+// Start may be after End for windows that span midnight (e.g. 22:00-07:00).
+type Window struct {
+	Start    time.Duration // Wall-clock time of day
+	End      time.Duration // Wall-clock time of day
+	Location *time.Location
+}
+
+// Builds a Window from "HH:MM" bounds and an IANA timezone name
+func Parse(start, end, timezone string) (Window, error) {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return Window{}, fmt.Errorf("invalid timezone %q: %w", timezone, err)
+	}
+
+	startOffset, err := ParseClock(start)
+	if err != nil {
+		return Window{}, err
+	}
+	endOffset, err := ParseClock(end)
+	if err != nil {
+		return Window{}, err
+	}
+	if startOffset == endOffset {
+		return Window{}, fmt.Errorf("quiet hours start and end must differ")
+	}
+
+	return Window{Start: startOffset, End: endOffset, Location: loc}, nil
+}
+
+// Converts "HH:MM" into an offset from midnight
+func ParseClock(clock string) (time.Duration, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM: %w", clock, err)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Returns when the window ends if now falls inside it
+func (w Window) Until(now time.Time) (time.Time, bool) {
+	local := now.In(w.Location)
+	offset := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute +
+		time.Duration(local.Second())*time.Second
+
+	switch {
+	case w.Start < w.End:
+		// Same-day window, e.g. 13:00-15:00
+		if offset >= w.Start && offset < w.End {
+			return w.endOn(local, 0), true
+		}
+	case offset >= w.Start:
+		// Overnight window, before midnight: ends tomorrow
+		return w.endOn(local, 1), true
+	case offset < w.End:
+		// Overnight window, after midnight: ends today
+		return w.endOn(local, 0), true
+	}
+
+	return time.Time{}, false
+}
+
+// Returns the wall-clock end of the window, days after the given local date
+func (w Window) endOn(local time.Time, days int) time.Time {
+	hours := int(w.End / time.Hour)
+	minutes := int((w.End % time.Hour) / time.Minute)
+	return time.Date(local.Year(), local.Month(), local.Day()+days, hours, minutes, 0, 0, w.Location)
+}
@@ -0,0 +1,120 @@
+package quiethours
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWindowUntil(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	require.NoError(t, err)
+
+	overnight := Window{Start: 22 * time.Hour, End: 7 * time.Hour, Location: newYork}
+	afternoon := Window{Start: 13 * time.Hour, End: 15*time.Hour + 30*time.Minute, Location: kolkata}
+
+	tests := []struct {
+		name   string
+		window Window
+		now    time.Time
+		want   time.Time
+		quiet  bool
+	}{
+		{
+			name:   "same day inside",
+			window: afternoon,
+			now:    time.Date(2025, 4, 1, 14, 0, 0, 0, kolkata),
+			want:   time.Date(2025, 4, 1, 15, 30, 0, 0, kolkata),
+			quiet:  true,
+		},
+		{
+			name:   "same day at start",
+			window: afternoon,
+			now:    time.Date(2025, 4, 1, 13, 0, 0, 0, kolkata),
+			want:   time.Date(2025, 4, 1, 15, 30, 0, 0, kolkata),
+			quiet:  true,
+		},
+		{
+			name:   "same day at end",
+			window: afternoon,
+			now:    time.Date(2025, 4, 1, 15, 30, 0, 0, kolkata),
+		},
+		{
+			name:   "same day before",
+			window: afternoon,
+			now:    time.Date(2025, 4, 1, 9, 0, 0, 0, kolkata),
+		},
+		{
+			name:   "overnight before midnight",
+			window: overnight,
+			now:    time.Date(2025, 4, 1, 23, 15, 0, 0, newYork),
+			want:   time.Date(2025, 4, 2, 7, 0, 0, 0, newYork),
+			quiet:  true,
+		},
+		{
+			name:   "overnight after midnight",
+			window: overnight,
+			now:    time.Date(2025, 4, 2, 3, 0, 0, 0, newYork),
+			want:   time.Date(2025, 4, 2, 7, 0, 0, 0, newYork),
+			quiet:  true,
+		},
+		{
+			name:   "overnight outside",
+			window: overnight,
+			now:    time.Date(2025, 4, 2, 12, 0, 0, 0, newYork),
+		},
+		{
+			name:   "overnight at end",
+			window: overnight,
+			now:    time.Date(2025, 4, 2, 7, 0, 0, 0, newYork),
+		},
+		{
+			name:   "now given in another zone",
+			window: overnight,
+			now:    time.Date(2025, 4, 2, 4, 0, 0, 0, time.UTC), // 00:00 in New York
+			want:   time.Date(2025, 4, 2, 7, 0, 0, 0, newYork),
+			quiet:  true,
+		},
+		{
+			name:   "ends on the far side of a DST change",
+			window: overnight,
+			now:    time.Date(2025, 3, 8, 23, 0, 0, 0, newYork),
+			want:   time.Date(2025, 3, 9, 11, 0, 0, 0, time.UTC), // 07:00 EDT
+			quiet:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, quiet := tt.window.Until(tt.now)
+			assert.Equal(t, tt.quiet, quiet)
+			if tt.quiet {
+				assert.True(t, tt.want.Equal(got), "want %s, got %s", tt.want, got)
+			} else {
+				assert.True(t, got.IsZero())
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	w, err := Parse("22:00", "07:30", "Europe/Berlin")
+	require.NoError(t, err)
+	assert.Equal(t, 22*time.Hour, w.Start)
+	assert.Equal(t, 7*time.Hour+30*time.Minute, w.End)
+	assert.Equal(t, "Europe/Berlin", w.Location.String())
+
+	for _, bounds := range [][3]string{
+		{"22:00", "07:00", "Mars/Olympus"},
+		{"25:00", "07:00", "UTC"},
+		{"22:00", "7pm", "UTC"},
+		{"08:00", "08:00", "UTC"},
+	} {
+		_, err := Parse(bounds[0], bounds[1], bounds[2])
+		assert.Error(t, err, "%v", bounds)
+	}
+}
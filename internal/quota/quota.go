@@ -0,0 +1,44 @@
+package quota
+
+// Matches any caller or channel
+const Wildcard = "*"
+
+// Quota caps how many notifications a caller may send on a channel.
+// Empty or "*" fields match anything; a limit of 0 means unlimited.
+type Quota struct {
+	Caller  string
+	Channel string
+	Daily   int
+	Monthly int
+}
+
+// Quotas is the set of configured quotas
+type Quotas []Quota
+
+// Returns the most specific quota for the caller and channel.
+// An exact caller outranks an exact channel.
+func (q Quotas) Lookup(caller, channel string) (Quota, bool) {
+	best, bestScore := Quota{}, -1
+	for _, quota := range q {
+		score := 0
+		switch quota.Caller {
+		case caller:
+			score += 2
+		case "", Wildcard:
+		default:
+			continue
+		}
+		switch quota.Channel {
+		case channel:
+			score++
+		case "", Wildcard:
+		default:
+			continue
+		}
+
+		if score > bestScore {
+			best, bestScore = quota, score
+		}
+	}
+	return best, bestScore >= 0
+}
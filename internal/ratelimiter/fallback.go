@@ -0,0 +1,161 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/officiallysidsingh/go-notify/internal/logging"
+)
+
+// What the limiter does while Redis is unavailable
+const (
+	FailClosed = "fail_closed" // Reject every request
+	FailOpen   = "fail_open"   // Allow every request
+	Local      = "local"       // Enforce limits per process in memory
+)
+
+// Mode reported while Redis is healthy
+const modeRedis = "redis"
+
+// Returned under the fail-closed policy while Redis is unavailable
+var ErrUnavailable = errors.New("rate limiter unavailable")
+
+var (
+	// 1 for the mode currently used to make decisions, 0 for the others
+	limiterMode = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "rate_limiter_mode",
+			Help: "Active rate limiter mode (redis, fail_closed, fail_open or local)",
+		},
+		[]string{"mode"},
+	)
+
+	limiterRedisErrors = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "rate_limiter_redis_errors_total",
+			Help: "Total number of failed Redis rate limit checks and health checks",
+		},
+	)
+
+	limiterFallbackDecisions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rate_limiter_fallback_decisions_total",
+			Help: "Total number of rate limit decisions made without Redis",
+		},
+		[]string{"mode", "allowed"},
+	)
+)
+
+// Init prometheus metrics
+func init() {
+	prometheus.MustRegister(limiterMode, limiterRedisErrors, limiterFallbackDecisions)
+}
+
+// Sets what happens to requests while Redis is unavailable
+func (rl *RateLimiter) SetFailurePolicy(policy string) error {
+	switch policy {
+	case "":
+		policy = FailClosed
+	case FailClosed, FailOpen, Local:
+	default:
+		return fmt.Errorf("unknown rate limiter failure policy: %q", policy)
+	}
+
+	rl.failurePolicy = policy
+	rl.reportMode()
+	return nil
+}
+
+// Pings Redis every interval until ctx is done, switching between Redis and
+// the failure policy as it goes down and comes back
+func (rl *RateLimiter) StartHealthCheck(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				pingCtx, cancel := context.WithTimeout(ctx, interval)
+				err := rl.client.Ping(pingCtx).Err()
+				cancel()
+
+				if err != nil {
+					limiterRedisErrors.Inc()
+					if rl.healthy.Load() {
+						rl.log.Warn("Rate limiter Redis health check failed, switching to failure policy", zap.String("policy", rl.failurePolicy), zap.Error(err))
+					}
+					rl.setHealthy(false)
+				} else if !rl.healthy.Load() {
+					rl.log.Info("Rate limiter Redis is healthy again")
+					rl.setHealthy(true)
+				}
+			}
+		}
+	}()
+}
+
+// Checks key in Redis, or applies the failure policy while Redis is down
+func (rl *RateLimiter) check(ctx context.Context, key string, limit int, window time.Duration) (Result, error) {
+	if rl.healthy.Load() {
+		result, err := rl.checkRedis(ctx, key, limit, window)
+		if err == nil {
+			return result, nil
+		}
+
+		// A cancelled request says nothing about Redis health
+		if ctx.Err() != nil {
+			return Result{}, err
+		}
+
+		limiterRedisErrors.Inc()
+		logging.FromContext(ctx, rl.log).Warn("Rate limiter Redis error, switching to failure policy", zap.String("policy", rl.failurePolicy), zap.Error(err))
+		rl.setHealthy(false)
+	}
+
+	return rl.fallback(key, limit, window)
+}
+
+// Decides without Redis according to the failure policy
+func (rl *RateLimiter) fallback(key string, limit int, window time.Duration) (Result, error) {
+	switch rl.failurePolicy {
+	case FailOpen:
+		limiterFallbackDecisions.WithLabelValues(FailOpen, "true").Inc()
+		return Result{Allowed: true, Limit: -1, Remaining: -1}, nil
+	case Local:
+		result := rl.local.check(key, limit, window)
+		limiterFallbackDecisions.WithLabelValues(Local, fmt.Sprint(result.Allowed)).Inc()
+		return result, nil
+	default:
+		limiterFallbackDecisions.WithLabelValues(FailClosed, "false").Inc()
+		return Result{}, ErrUnavailable
+	}
+}
+
+// Records Redis health and updates the mode gauge
+func (rl *RateLimiter) setHealthy(healthy bool) {
+	rl.healthy.Store(healthy)
+	rl.reportMode()
+}
+
+func (rl *RateLimiter) reportMode() {
+	active := modeRedis
+	if !rl.healthy.Load() {
+		active = rl.failurePolicy
+	}
+
+	for _, mode := range []string{modeRedis, FailClosed, FailOpen, Local} {
+		value := 0.0
+		if mode == active {
+			value = 1
+		}
+		limiterMode.WithLabelValues(mode).Set(value)
+	}
+}
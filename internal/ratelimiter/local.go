@@ -0,0 +1,66 @@
+package ratelimiter
+
+import (
+	"sync"
+	"time"
+)
+
+// How often expired local windows are dropped
+const localSweepInterval = time.Minute
+
+// In-memory fixed window limiter used while Redis is unavailable.
+// Limits apply per process, so replicas together may allow more.
+type localLimiter struct {
+	mu        sync.Mutex
+	windows   map[string]*localWindow
+	lastSweep time.Time
+}
+
+type localWindow struct {
+	count   int
+	resetAt time.Time
+}
+
+func newLocalLimiter() *localLimiter {
+	return &localLimiter{
+		windows:   make(map[string]*localWindow),
+		lastSweep: time.Now(),
+	}
+}
+
+// Consumes one request for key against limit per window
+func (l *localLimiter) check(key string, limit int, window time.Duration) Result {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > localSweepInterval {
+		for k, w := range l.windows {
+			if !now.Before(w.resetAt) {
+				delete(l.windows, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	// Window length is part of the key since rules may share one
+	windowKey := key + "@" + window.String()
+	w, ok := l.windows[windowKey]
+	if !ok || !now.Before(w.resetAt) {
+		w = &localWindow{resetAt: now.Add(window)}
+		l.windows[windowKey] = w
+	}
+	w.count++
+
+	result := Result{
+		Allowed:   w.count <= limit,
+		Limit:     limit,
+		Remaining: max(limit-w.count, 0),
+		ResetAt:   w.resetAt,
+	}
+	if !result.Allowed {
+		result.RetryAfter = w.resetAt.Sub(now)
+	}
+	return result
+}
@@ -3,43 +3,161 @@ package ratelimiter
 import (
 	"context"
 	"fmt"
+	"math/rand"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
 )
 
-// Implements a simple counter-based rate limiter
+// Supported rate limiting algorithms
+const (
+	FixedWindow          = "fixed_window"
+	SlidingWindowLog     = "sliding_window_log"
+	SlidingWindowCounter = "sliding_window_counter"
+	TokenBucket          = "token_bucket"
+)
+
+// Result describes the outcome of a rate limit check
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	ResetAt    time.Time     // When the full quota is available again
+	RetryAfter time.Duration // How long to wait before retrying, if not allowed
+}
+
+// Implements a Redis-backed rate limiter using atomic Lua scripts
 type RateLimiter struct {
-	client *redis.Client
-	limit  int
-	window time.Duration
+	client         *redis.Client
+	limit          int
+	window         time.Duration
+	algorithm      string
+	rules          []Rule
+	exemptPriority string
+	failurePolicy  string
+	healthy        atomic.Bool
+	local          *localLimiter
+	log            *zap.Logger
 }
 
-// Creates a new RateLimiter
-func NewRateLimiter(addr string, limit int, window time.Duration) *RateLimiter {
+// Creates a new RateLimiter. An empty algorithm selects the fixed window.
+func NewRateLimiter(addr string, limit int, window time.Duration, algorithm string, logger *zap.Logger) (*RateLimiter, error) {
+	if algorithm == "" {
+		algorithm = FixedWindow
+	}
+
+	switch algorithm {
+	case FixedWindow, SlidingWindowLog, SlidingWindowCounter, TokenBucket:
+	default:
+		return nil, fmt.Errorf("unknown rate limit algorithm: %q", algorithm)
+	}
+
+	if limit <= 0 || window < time.Millisecond {
+		return nil, fmt.Errorf("invalid rate limit: %d per %s", limit, window)
+	}
+
 	client := redis.NewClient(&redis.Options{
 		Addr: addr,
 	})
 
-	return &RateLimiter{
-		client: client,
-		limit:  limit,
-		window: window,
+	rl := &RateLimiter{
+		client:        client,
+		limit:         limit,
+		window:        window,
+		algorithm:     algorithm,
+		failurePolicy: FailClosed,
+		local:         newLocalLimiter(),
+		log:           logger,
 	}
+	rl.setHealthy(true)
+
+	return rl, nil
 }
 
-// Returns true if key (userID) is within rate limits
-func (rl *RateLimiter) Allow(ctx context.Context, key string) (bool, error) {
-	fullKey := fmt.Sprintf("rate:%s", key)
-	count, err := rl.client.Incr(ctx, fullKey).Result()
+// Consumes quota for the request and reports whether it is allowed, along with
+// the limit, remaining quota and reset time of the most restrictive limit hit.
+// Exempt requests report a Limit and Remaining of -1.
+func (rl *RateLimiter) Allow(ctx context.Context, req Request) (Result, error) {
+	return rl.evaluate(ctx, req)
+}
+
+// Consumes one request for key in Redis and reports the remaining quota
+func (rl *RateLimiter) checkRedis(ctx context.Context, key string, limit int, window time.Duration) (Result, error) {
+	now := time.Now()
+	nowMs := now.UnixMilli()
+	windowMs := window.Milliseconds()
+
+	var (
+		values []interface{}
+		err    error
+	)
+
+	switch rl.algorithm {
+	case SlidingWindowLog:
+		member := strconv.FormatInt(now.UnixNano(), 10) + "-" + strconv.Itoa(rand.Int())
+		values, err = runScript(ctx, rl.client, slidingWindowLogScript,
+			[]string{fmt.Sprintf("rate:%s:log", key)},
+			nowMs, windowMs, limit, member,
+		)
+	case SlidingWindowCounter:
+		current := nowMs / windowMs
+		values, err = runScript(ctx, rl.client, slidingWindowCounterScript,
+			[]string{
+				fmt.Sprintf("rate:%s:swc:%d", key, current),
+				fmt.Sprintf("rate:%s:swc:%d", key, current-1),
+			},
+			nowMs, windowMs, limit,
+		)
+	case TokenBucket:
+		refillMs := float64(windowMs) / float64(limit)
+		values, err = runScript(ctx, rl.client, tokenBucketScript,
+			[]string{fmt.Sprintf("rate:%s:bucket", key)},
+			nowMs, refillMs, limit,
+		)
+	default:
+		values, err = runScript(ctx, rl.client, fixedWindowScript,
+			[]string{fmt.Sprintf("rate:%s", key)},
+			nowMs, windowMs, limit,
+		)
+	}
 	if err != nil {
-		return false, err
+		return Result{}, err
 	}
-	if count == 1 {
-		rl.client.Expire(ctx, fullKey, rl.window)
+
+	return Result{
+		Allowed:    values[0] == int64(1),
+		Limit:      limit,
+		Remaining:  int(toInt64(values[1])),
+		ResetAt:    now.Add(time.Duration(toInt64(values[2])) * time.Millisecond),
+		RetryAfter: time.Duration(toInt64(values[3])) * time.Millisecond,
+	}, nil
+}
+
+// Runs a rate limit script and checks the shape of its reply
+func runScript(
+	ctx context.Context,
+	client *redis.Client,
+	script *redis.Script,
+	keys []string,
+	args ...interface{},
+) ([]interface{}, error) {
+	reply, err := script.Run(ctx, client, keys, args...).Result()
+	if err != nil {
+		return nil, err
 	}
-	if count > int64(rl.limit) {
-		return false, nil
+
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 4 {
+		return nil, fmt.Errorf("unexpected rate limit script reply: %v", reply)
 	}
-	return true, nil
+	return values, nil
+}
+
+// Converts a Redis integer reply, treating anything else as 0
+func toInt64(v interface{}) int64 {
+	n, _ := v.(int64)
+	return n
 }
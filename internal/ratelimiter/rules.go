@@ -0,0 +1,145 @@
+package ratelimiter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Matches any channel, priority or category in a rule
+const Wildcard = "*"
+
+// Request describes the notification being rate limited
+type Request struct {
+	Tenant   string // Keeps each tenant's counters separate
+	UserID   string
+	Channel  string
+	Priority string
+	Category string
+}
+
+// Rule limits a user's notifications that match its channel, priority and category.
+// Empty or "*" fields match anything.
+type Rule struct {
+	Channel  string
+	Priority string
+	Category string
+	Limit    int
+	Window   time.Duration
+}
+
+// Identifies whose quota the request consumes
+func (req Request) subject() string {
+	if req.Tenant == "" {
+		return req.UserID
+	}
+	return req.Tenant + ":" + req.UserID
+}
+
+// Reports whether the rule applies to the request
+func (r Rule) matches(req Request) bool {
+	return matchField(r.Channel, req.Channel) &&
+		matchField(r.Priority, req.Priority) &&
+		matchField(r.Category, req.Category)
+}
+
+// Names what the rule matches for a user
+func (r Rule) scope(userID string) string {
+	return strings.Join([]string{
+		userID,
+		orWildcard(r.Channel),
+		orWildcard(r.Priority),
+		orWildcard(r.Category),
+	}, ":")
+}
+
+// Builds the per-user counter key; requests matching the same rule share it.
+// Limit and window are part of it so rules differing only in those, or a rule
+// whose config changed, never share a counter.
+func (r Rule) key(userID string) string {
+	return fmt.Sprintf("%s@%d/%s", r.scope(userID), r.Limit, r.Window)
+}
+
+// Adds a rule. Requests matching any rule are limited by those rules instead
+// of the per-user limit, so one channel can't exhaust another's quota.
+func (rl *RateLimiter) AddRule(rule Rule) error {
+	if rule.Limit <= 0 || rule.Window < time.Millisecond {
+		return fmt.Errorf("invalid rate limit rule %s: %d per %s", rule.scope("*"), rule.Limit, rule.Window)
+	}
+	rl.rules = append(rl.rules, rule)
+	return nil
+}
+
+// Exempts notifications with the given priority from all limits
+func (rl *RateLimiter) ExemptPriority(priority string) {
+	rl.exemptPriority = priority
+}
+
+// Checks every matching rule, or the per-user limit when no rule matches.
+// A request must pass all matching rules; quota consumed by rules that passed
+// is not refunded when another rule rejects the request.
+func (rl *RateLimiter) evaluate(ctx context.Context, req Request) (Result, error) {
+	if rl.exemptPriority != "" && req.Priority == rl.exemptPriority {
+		return Result{Allowed: true, Limit: -1, Remaining: -1}, nil
+	}
+
+	var (
+		result  Result
+		matched bool
+	)
+	for _, rule := range rl.rules {
+		if !rule.matches(req) {
+			continue
+		}
+
+		ruleResult, err := rl.check(ctx, "rule:"+rule.key(req.subject()), rule.Limit, rule.Window)
+		if err != nil {
+			return Result{}, err
+		}
+
+		if !matched {
+			result, matched = ruleResult, true
+		} else {
+			result = mostRestrictive(result, ruleResult)
+		}
+	}
+
+	if !matched {
+		return rl.check(ctx, req.subject(), rl.limit, rl.window)
+	}
+	return result, nil
+}
+
+// Picks the result the caller should act on: a rejection over an allowance,
+// the longest wait between rejections, and the least quota between allowances
+func mostRestrictive(a, b Result) Result {
+	switch {
+	case a.Allowed != b.Allowed:
+		if !a.Allowed {
+			return a
+		}
+		return b
+	case !a.Allowed:
+		if b.RetryAfter > a.RetryAfter {
+			return b
+		}
+		return a
+	default:
+		if b.Remaining < a.Remaining {
+			return b
+		}
+		return a
+	}
+}
+
+func matchField(pattern, value string) bool {
+	return pattern == "" || pattern == Wildcard || pattern == value
+}
+
+func orWildcard(field string) string {
+	if field == "" {
+		return Wildcard
+	}
+	return field
+}
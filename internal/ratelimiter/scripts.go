@@ -0,0 +1,138 @@
+package ratelimiter
+
+import "github.com/go-redis/redis/v8"
+
+// All scripts return {allowed, remaining, reset_ms, retry_after_ms}.
+// The current time is passed in ms as ARGV[1] so the scripts stay deterministic.
+
+// Fixed window counter. INCR and PEXPIRE run atomically, and a key left
+// without a TTL is healed so a user can never be blocked permanently.
+// ARGV: now, window_ms, limit
+var fixedWindowScript = redis.NewScript(`
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+local count = redis.call('INCR', KEYS[1])
+local ttl = redis.call('PTTL', KEYS[1])
+if count == 1 or ttl < 0 then
+	redis.call('PEXPIRE', KEYS[1], window)
+	ttl = window
+end
+
+local allowed = 0
+local retry = ttl
+if count <= limit then
+	allowed = 1
+	retry = 0
+end
+
+return {allowed, math.max(limit - count, 0), ttl, retry}
+`)
+
+// Sliding window log. Keeps one sorted set member per accepted request.
+// ARGV: now, window_ms, limit, unique member
+var slidingWindowLogScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local count = redis.call('ZCARD', KEYS[1])
+
+local allowed = 0
+if count < limit then
+	redis.call('ZADD', KEYS[1], now, ARGV[4])
+	count = count + 1
+	allowed = 1
+end
+redis.call('PEXPIRE', KEYS[1], window)
+
+local reset = 0
+local retry = 0
+if count > 0 then
+	local newest = redis.call('ZRANGE', KEYS[1], -1, -1, 'WITHSCORES')
+	reset = tonumber(newest[2]) + window - now
+end
+if allowed == 0 then
+	local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+	retry = math.max(tonumber(oldest[2]) + window - now, 1)
+end
+
+return {allowed, limit - count, reset, retry}
+`)
+
+// Sliding window counter. Weights the previous fixed window by how much of
+// it still overlaps the sliding window.
+// KEYS: current window, previous window. ARGV: now, window_ms, limit
+var slidingWindowCounterScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+local elapsed = now % window
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+local previous = tonumber(redis.call('GET', KEYS[2]) or '0')
+local estimated = math.floor(previous * (window - elapsed) / window) + current
+
+local allowed = 0
+if estimated < limit then
+	current = redis.call('INCR', KEYS[1])
+	redis.call('PEXPIRE', KEYS[1], window * 2)
+	estimated = estimated + 1
+	allowed = 1
+end
+
+local reset = window - elapsed
+if current > 0 then
+	reset = reset + window
+end
+
+local retry = 0
+if allowed == 0 then
+	if current >= limit then
+		-- Wait for this window to become the previous one and decay enough
+		retry = window - elapsed + math.max(window - math.floor(window * limit / current), 1)
+	elseif previous == 0 then
+		retry = window - elapsed
+	else
+		local needed = window - math.floor(window * (limit - current) / previous)
+		retry = math.max(needed - elapsed, 1)
+	end
+end
+
+return {allowed, math.max(limit - estimated, 0), reset, retry}
+`)
+
+// Token bucket holding up to limit tokens, refilled at one token per refill_ms.
+// ARGV: now, refill_ms, limit
+var tokenBucketScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local refill = tonumber(ARGV[2])
+local capacity = tonumber(ARGV[3])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now
+end
+
+tokens = math.min(capacity, tokens + math.max(now - ts, 0) / refill)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(capacity * refill))
+
+local retry = 0
+if allowed == 0 then
+	retry = math.ceil((1 - tokens) * refill)
+end
+
+return {allowed, math.floor(tokens), math.ceil((capacity - tokens) * refill), retry}
+`)
@@ -0,0 +1,206 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// One script call and its expected {allowed, remaining, reset_ms, retry_after_ms}
+type scriptStep struct {
+	now       int64
+	allowed   int64
+	remaining int64
+	reset     int64
+	retry     int64
+}
+
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return mr, client
+}
+
+func assertStep(t *testing.T, values []interface{}, want scriptStep) {
+	t.Helper()
+	assert.Equal(t, want.allowed, toInt64(values[0]), "allowed")
+	assert.Equal(t, want.remaining, toInt64(values[1]), "remaining")
+	assert.Equal(t, want.reset, toInt64(values[2]), "reset")
+	assert.Equal(t, want.retry, toInt64(values[3]), "retry")
+}
+
+func TestScripts(t *testing.T) {
+	const (
+		window = int64(1000)
+		limit  = 2
+	)
+
+	tests := []struct {
+		name   string
+		script *redis.Script
+		keys   []string
+		setup  func(*miniredis.Miniredis)
+		args   func(step int, now int64) []interface{}
+		steps  []scriptStep
+	}{
+		{
+			name:   "fixed window",
+			script: fixedWindowScript,
+			keys:   []string{"rate:k"},
+			args: func(_ int, now int64) []interface{} {
+				return []interface{}{now, window, limit}
+			},
+			steps: []scriptStep{
+				{now: 0, allowed: 1, remaining: 1, reset: 1000},
+				{now: 100, allowed: 1, remaining: 0, reset: 1000},
+				{now: 200, allowed: 0, remaining: 0, reset: 1000, retry: 1000},
+			},
+		},
+		{
+			name:   "fixed window heals a key without TTL",
+			script: fixedWindowScript,
+			keys:   []string{"rate:k"},
+			setup: func(mr *miniredis.Miniredis) {
+				require.NoError(t, mr.Set("rate:k", "5"))
+			},
+			args: func(_ int, now int64) []interface{} {
+				return []interface{}{now, window, limit}
+			},
+			steps: []scriptStep{
+				{now: 0, allowed: 0, remaining: 0, reset: 1000, retry: 1000},
+			},
+		},
+		{
+			name:   "sliding window log",
+			script: slidingWindowLogScript,
+			keys:   []string{"rate:k:log"},
+			args: func(step int, now int64) []interface{} {
+				return []interface{}{now, window, limit, step}
+			},
+			steps: []scriptStep{
+				{now: 0, allowed: 1, remaining: 1, reset: 1000},
+				{now: 100, allowed: 1, remaining: 0, reset: 1000},
+				{now: 200, allowed: 0, remaining: 0, reset: 900, retry: 800},
+				// The request at 0 has left the window
+				{now: 1000, allowed: 1, remaining: 0, reset: 1000},
+			},
+		},
+		{
+			name:   "sliding window counter",
+			script: slidingWindowCounterScript,
+			keys:   []string{"rate:k:swc:1", "rate:k:swc:0"},
+			args: func(_ int, now int64) []interface{} {
+				return []interface{}{now, window, limit}
+			},
+			steps: []scriptStep{
+				{now: 1000, allowed: 1, remaining: 1, reset: 2000},
+				{now: 1500, allowed: 1, remaining: 0, reset: 1500},
+				// Full current window: wait until it has decayed as the previous one
+				{now: 1500, allowed: 0, remaining: 0, reset: 1500, retry: 501},
+			},
+		},
+		{
+			name:   "sliding window counter weighs the previous window",
+			script: slidingWindowCounterScript,
+			keys:   []string{"rate:k:swc:1", "rate:k:swc:0"},
+			setup: func(mr *miniredis.Miniredis) {
+				require.NoError(t, mr.Set("rate:k:swc:0", "3"))
+			},
+			args: func(_ int, now int64) []interface{} {
+				return []interface{}{now, window, limit}
+			},
+			steps: []scriptStep{
+				// Half of the previous window's 3 still counts
+				{now: 1500, allowed: 1, remaining: 0, reset: 1500},
+				{now: 1500, allowed: 0, remaining: 0, reset: 1500, retry: 167},
+			},
+		},
+		{
+			name:   "token bucket",
+			script: tokenBucketScript,
+			keys:   []string{"rate:k:bucket"},
+			args: func(_ int, now int64) []interface{} {
+				return []interface{}{now, float64(window) / limit, limit}
+			},
+			steps: []scriptStep{
+				{now: 0, allowed: 1, remaining: 1, reset: 500},
+				{now: 0, allowed: 1, remaining: 0, reset: 1000},
+				{now: 250, allowed: 0, remaining: 0, reset: 750, retry: 250},
+				// Refilled one token since the last accepted request
+				{now: 500, allowed: 1, remaining: 0, reset: 1000},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr, client := newTestRedis(t)
+			if tt.setup != nil {
+				tt.setup(mr)
+			}
+
+			for i, step := range tt.steps {
+				values, err := runScript(context.Background(), client, tt.script, tt.keys, tt.args(i, step.now)...)
+				require.NoError(t, err)
+				assertStep(t, values, step)
+			}
+		})
+	}
+}
+
+func TestFixedWindowSetsMissingTTL(t *testing.T) {
+	mr, client := newTestRedis(t)
+	require.NoError(t, mr.Set("rate:k", "1"))
+
+	_, err := runScript(context.Background(), client, fixedWindowScript, []string{"rate:k"}, 0, 1000, 10)
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, mr.TTL("rate:k"))
+}
+
+func TestFixedWindowResetsAfterWindow(t *testing.T) {
+	mr, client := newTestRedis(t)
+	args := []interface{}{0, 1000, 1}
+
+	values, err := runScript(context.Background(), client, fixedWindowScript, []string{"rate:k"}, args...)
+	require.NoError(t, err)
+	assertStep(t, values, scriptStep{allowed: 1, remaining: 0, reset: 1000})
+
+	values, err = runScript(context.Background(), client, fixedWindowScript, []string{"rate:k"}, args...)
+	require.NoError(t, err)
+	assertStep(t, values, scriptStep{allowed: 0, remaining: 0, reset: 1000, retry: 1000})
+
+	mr.FastForward(time.Second)
+
+	values, err = runScript(context.Background(), client, fixedWindowScript, []string{"rate:k"}, args...)
+	require.NoError(t, err)
+	assertStep(t, values, scriptStep{allowed: 1, remaining: 0, reset: 1000})
+}
+
+func TestRulesWithSameScopeKeepSeparateCounters(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rl, err := NewRateLimiter(mr.Addr(), 10, time.Minute, FixedWindow, zap.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, rl.AddRule(Rule{Channel: "email", Limit: 1, Window: time.Minute}))
+	require.NoError(t, rl.AddRule(Rule{Channel: "email", Limit: 5, Window: time.Hour}))
+
+	req := Request{Tenant: "default", UserID: "u1", Channel: "email"}
+
+	first, err := rl.Allow(context.Background(), req)
+	require.NoError(t, err)
+	assert.True(t, first.Allowed)
+	assert.Equal(t, 0, first.Remaining)
+	assert.Len(t, mr.Keys(), 2)
+
+	second, err := rl.Allow(context.Background(), req)
+	require.NoError(t, err)
+	assert.False(t, second.Allowed)
+	assert.Equal(t, 1, second.Limit)
+}
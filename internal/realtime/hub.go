@@ -0,0 +1,259 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+
+	"github.com/officiallysidsingh/go-notify/internal/logging"
+)
+
+// Events buffered per session before it counts as too slow
+const sessionBuffer = 64
+
+// Session is one open WebSocket or SSE connection of a user
+type Session struct {
+	TenantID string
+	UserID   string
+
+	channel   string
+	events    chan Event
+	overflow  []Event // Events that didn't fit when the session fell behind; guarded by the hub's mu
+	unsent    []Event // Events the connection took but failed to send
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Events for the user, in publish order
+func (s *Session) Events() <-chan Event {
+	return s.events
+}
+
+// Closed when the hub drops the session because it fell behind
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *Session) close() {
+	s.closeOnce.Do(func() { close(s.done) })
+}
+
+// Hands back events the connection took but failed to send, so they are
+// kept when the session disconnects. Called from the connection's goroutine.
+func (s *Session) Unsent(events ...Event) {
+	s.unsent = append(s.unsent, events...)
+}
+
+// Takes the events still buffered for a session the hub no longer sends to
+func (s *Session) drain() []Event {
+	var events []Event
+	for {
+		select {
+		case e := <-s.events:
+			events = append(events, e)
+		default:
+			return events
+		}
+	}
+}
+
+// Hub fans events out to the sessions connected to this replica. It
+// subscribes to a user's channel while they have at least one session here.
+type Hub struct {
+	client    *redis.Client
+	pubsub    *redis.PubSub
+	retention time.Duration
+	log       *zap.Logger
+
+	mu       sync.Mutex
+	sessions map[string]map[*Session]struct{} // By channel
+}
+
+// Creates a new Hub
+func NewHub(addr string, retention time.Duration, logger *zap.Logger) *Hub {
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	return &Hub{
+		client:    client,
+		pubsub:    client.Subscribe(context.Background()),
+		retention: retention,
+		log:       logger,
+		sessions:  make(map[string]map[*Session]struct{}),
+	}
+}
+
+// Delivers published events to local sessions until ctx is done
+func (h *Hub) Run(ctx context.Context) {
+	messages := h.pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := h.pubsub.Close(); err != nil {
+				h.log.Error("Failed to close realtime subscription", zap.Error(err))
+			}
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			h.dispatch(msg)
+		}
+	}
+}
+
+// Opens a session for the user and returns the events kept while they were offline
+func (h *Hub) Connect(ctx context.Context, tenantID, userID string) (*Session, []Event, error) {
+	s := &Session{
+		TenantID: tenantID,
+		UserID:   userID,
+		channel:  channelName(tenantID, userID),
+		events:   make(chan Event, sessionBuffer),
+		done:     make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	sessions, subscribed := h.sessions[s.channel]
+	if !subscribed {
+		// Subscribe before taking the kept events so none fall in between
+		if err := h.pubsub.Subscribe(ctx, s.channel); err != nil {
+			h.mu.Unlock()
+			return nil, nil, fmt.Errorf("failed to subscribe: %w", err)
+		}
+		sessions = make(map[*Session]struct{})
+		h.sessions[s.channel] = sessions
+	}
+	sessions[s] = struct{}{}
+	h.mu.Unlock()
+
+	replay, err := h.takeKept(ctx, tenantID, userID)
+	if err != nil {
+		h.Disconnect(s)
+		return nil, nil, err
+	}
+	return s, replay, nil
+}
+
+// Closes the session, unsubscribing once the user has none left here.
+// Events the session never sent, whether handed back, still buffered or
+// overflowed, are kept in that order so the user's next connection
+// replays them. Called from the connection's goroutine.
+func (h *Hub) Disconnect(s *Session) {
+	h.mu.Lock()
+	h.remove(s)
+	overflow := s.overflow
+	s.overflow = nil
+	h.mu.Unlock()
+
+	// Removed sessions get no more events, so the buffer can't grow behind us
+	left := append(s.unsent, s.drain()...)
+	left = append(left, overflow...)
+	s.unsent = nil
+	if len(left) > 0 {
+		h.rekeep(s, left)
+	}
+}
+
+// Removes a session. Callers hold h.mu.
+func (h *Hub) remove(s *Session) {
+	s.close()
+
+	sessions, ok := h.sessions[s.channel]
+	if !ok {
+		return
+	}
+	if _, ok := sessions[s]; !ok {
+		return
+	}
+	delete(sessions, s)
+	if len(sessions) > 0 {
+		return
+	}
+
+	delete(h.sessions, s.channel)
+	if err := h.pubsub.Unsubscribe(context.Background(), s.channel); err != nil {
+		h.log.Error("Failed to unsubscribe", zap.String("redis_channel", s.channel), zap.Error(err))
+	}
+}
+
+// Hands an event to each session of its user. Sessions that fell behind are
+// dropped, holding on to the event so it is kept along with what they had
+// buffered when their connection disconnects.
+func (h *Hub) dispatch(msg *redis.Message) {
+	var e Event
+	if err := json.Unmarshal([]byte(msg.Payload), &e); err != nil {
+		h.log.Warn("Dropping invalid realtime event", zap.String("redis_channel", msg.Channel), zap.Error(err))
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.sessions[msg.Channel] {
+		select {
+		case s.events <- e:
+		default:
+			h.log.Warn("Dropped slow realtime session", logging.UserID(s.UserID))
+			s.overflow = append(s.overflow, e)
+			h.remove(s)
+		}
+	}
+}
+
+// Keeps events again for the user's next connection, in order.
+// They get a fresh retention.
+func (h *Hub) rekeep(s *Session, events []Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	data := make([][]byte, 0, len(events))
+	for _, e := range events {
+		d, err := json.Marshal(e)
+		if err != nil {
+			h.log.Error("Failed to encode realtime event", logging.NotificationID(e.ID), zap.Error(err))
+			continue
+		}
+		data = append(data, d)
+	}
+	if err := keep(ctx, h.client, h.retention, s.TenantID, s.UserID, data...); err != nil {
+		h.log.Error("Failed to keep realtime events", logging.UserID(s.UserID), zap.Int("events", len(data)), zap.Error(err))
+	}
+}
+
+// Removes and returns the unexpired events kept for the user, oldest first.
+// Taking them at once keeps two sessions from both replaying them; callers
+// hand back the ones they fail to send with Session.Unsent.
+func (h *Hub) takeKept(ctx context.Context, tenantID, userID string) ([]Event, error) {
+	key := offlineKey(tenantID, userID)
+
+	var kept *redis.StringSliceCmd
+	_, err := h.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		kept = pipe.ZRangeByScore(ctx, key, &redis.ZRangeBy{
+			Min: strconv.FormatInt(time.Now().UnixMilli(), 10),
+			Max: "+inf",
+		})
+		pipe.Del(ctx, key)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kept realtime events: %w", err)
+	}
+
+	events := make([]Event, 0, len(kept.Val()))
+	for _, data := range kept.Val() {
+		var e Event
+		if err := json.Unmarshal([]byte(data), &e); err != nil {
+			h.log.Warn("Skipping invalid kept realtime event", zap.Error(err))
+			continue
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
@@ -0,0 +1,70 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func dispatchEvent(t *testing.T, h *Hub, s *Session, id int64) {
+	t.Helper()
+	data, err := json.Marshal(Event{ID: id})
+	require.NoError(t, err)
+	h.dispatch(&redis.Message{Channel: s.channel, Payload: string(data)})
+}
+
+func eventIDs(events []Event) []int64 {
+	ids := make([]int64, 0, len(events))
+	for _, e := range events {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+func TestDisconnectKeepsUnsentEvents(t *testing.T) {
+	mr := miniredis.RunT(t)
+	h := NewHub(mr.Addr(), time.Hour, zap.NewNop())
+	ctx := context.Background()
+
+	s, replay, err := h.Connect(ctx, "acme", "u1")
+	require.NoError(t, err)
+	assert.Empty(t, replay)
+
+	// One more than fits in the buffer drops the session
+	want := make([]int64, 0, sessionBuffer+2)
+	for id := int64(1); id <= sessionBuffer+2; id++ {
+		want = append(want, id)
+	}
+	for _, id := range want[1:] {
+		dispatchEvent(t, h, s, id)
+	}
+	<-s.Done()
+
+	// The connection took the first buffered event but failed to send it,
+	// after failing to send event 1 from its replay
+	taken := <-s.Events()
+	s.Unsent(Event{ID: 1}, taken)
+	h.Disconnect(s)
+
+	_, replay, err = h.Connect(ctx, "acme", "u1")
+	require.NoError(t, err)
+	assert.Equal(t, want, eventIDs(replay))
+}
+
+func TestDisconnectWithNothingLeft(t *testing.T) {
+	mr := miniredis.RunT(t)
+	h := NewHub(mr.Addr(), time.Hour, zap.NewNop())
+
+	s, _, err := h.Connect(context.Background(), "acme", "u1")
+	require.NoError(t, err)
+	h.Disconnect(s)
+
+	assert.False(t, mr.Exists(offlineKey("acme", "u1")))
+}
@@ -0,0 +1,52 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Publisher hands events from the workers to the gateway replicas
+type Publisher struct {
+	client    *redis.Client
+	retention time.Duration
+}
+
+// Creates a new Publisher keeping events for offline users for retention
+func NewPublisher(addr string, retention time.Duration) *Publisher {
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+
+	return &Publisher{
+		client:    redis.NewClient(&redis.Options{Addr: addr}),
+		retention: retention,
+	}
+}
+
+// Sends the event to the user's open sessions on every replica. When the
+// user has none it is kept and replayed on their next connection. Reports
+// whether a session received it live.
+func (p *Publisher) Publish(ctx context.Context, tenantID, userID string, e Event) (bool, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return false, err
+	}
+
+	// The count is the number of replicas the user is connected to
+	receivers, err := p.client.Publish(ctx, channelName(tenantID, userID), data).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to publish realtime event: %w", err)
+	}
+	if receivers > 0 {
+		return true, nil
+	}
+
+	if err := keep(ctx, p.client, p.retention, tenantID, userID, data); err != nil {
+		return false, fmt.Errorf("failed to keep realtime event: %w", err)
+	}
+	return false, nil
+}
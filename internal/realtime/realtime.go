@@ -0,0 +1,55 @@
+package realtime
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Used when realtime.retention is not configured
+const DefaultRetention = 24 * time.Hour
+
+// Event is a notification pushed to a user's browser sessions
+type Event struct {
+	ID        int64     `json:"id"` // Notification ID
+	Title     string    `json:"title,omitempty"`
+	Message   string    `json:"message"`
+	Priority  string    `json:"priority,omitempty"`
+	Category  string    `json:"category,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Pub/sub channel of a user's sessions across all gateway replicas.
+// Tenant IDs contain no colon, so the key is unambiguous.
+func channelName(tenantID, userID string) string {
+	return "realtime:" + tenantID + ":" + userID
+}
+
+// Sorted set of events kept for a user while offline, scored by expiry
+func offlineKey(tenantID, userID string) string {
+	return "realtime:offline:" + tenantID + ":" + userID
+}
+
+// Keeps encoded events for the user's next connection. Events kept together
+// expire a millisecond apart so they are replayed in the order given.
+func keep(ctx context.Context, client *redis.Client, retention time.Duration, tenantID, userID string, data ...[]byte) error {
+	key := offlineKey(tenantID, userID)
+	now := time.Now()
+	expiry := now.Add(retention).UnixMilli()
+
+	_, err := client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, d := range data {
+			pipe.ZAdd(ctx, key, &redis.Z{
+				Score:  float64(expiry + int64(i)),
+				Member: d,
+			})
+		}
+		// Drop what expired since the last write
+		pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.UnixMilli(), 10))
+		pipe.Expire(ctx, key, retention)
+		return nil
+	})
+	return err
+}
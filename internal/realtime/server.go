@@ -0,0 +1,286 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/net/websocket"
+
+	"github.com/officiallysidsingh/go-notify/internal/auth"
+	"github.com/officiallysidsingh/go-notify/internal/logging"
+	"github.com/officiallysidsingh/go-notify/internal/tenant"
+)
+
+const (
+	// Keeps idle connections open through proxies
+	heartbeatInterval = 25 * time.Second
+	// Max time to write one message before the client counts as gone
+	writeTimeout = 10 * time.Second
+)
+
+// message is the WebSocket frame; SSE uses the type as the event name
+type message struct {
+	Type         string `json:"type"` // "notification" or "ping"
+	Notification *Event `json:"notification,omitempty"`
+}
+
+// Server holds the browser connections of logged-in users
+type Server struct {
+	hub      *Hub
+	verifier *auth.JWTVerifier
+	tenants  *tenant.Registry
+	origins  []string
+	log      *zap.Logger
+}
+
+// Creates a new Server. Without a verifier users are taken from the
+// user_id and tenant_id query parameters, which is only fit for development.
+// Pages on the gateway's own host and the given origins may open WebSockets.
+func NewServer(hub *Hub, verifier *auth.JWTVerifier, tenants *tenant.Registry, origins []string, logger *zap.Logger) *Server {
+	return &Server{
+		hub:      hub,
+		verifier: verifier,
+		tenants:  tenants,
+		origins:  origins,
+		log:      logger,
+	}
+}
+
+// Returns the HTTP handler serving the WebSocket and SSE endpoints
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/realtime/ws", s.serveWebSocket)
+	mux.HandleFunc("GET /v1/realtime/sse", s.serveSSE)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	return mux
+}
+
+// Identifies the user from a bearer token, sent in the Authorization header
+// or, as browsers can't set headers on WebSocket and EventSource, the
+// access_token query parameter
+func (s *Server) authenticate(r *http.Request) (tenantID, userID string, err error) {
+	if s.verifier == nil {
+		tenantID, userID = r.URL.Query().Get("tenant_id"), r.URL.Query().Get("user_id")
+	} else {
+		token := r.URL.Query().Get("access_token")
+		if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+			token = strings.TrimPrefix(header, "Bearer ")
+		}
+		if token == "" {
+			return "", "", auth.ErrUnauthenticated
+		}
+
+		principal, err := s.verifier.Verify(token, time.Now())
+		if err != nil {
+			return "", "", fmt.Errorf("%w: %v", auth.ErrUnauthenticated, err)
+		}
+		tenantID, userID = principal.Tenant, principal.Subject
+	}
+
+	if userID == "" {
+		return "", "", fmt.Errorf("%w: no user", auth.ErrUnauthenticated)
+	}
+	t, err := s.tenants.Get(tenantID)
+	if err != nil {
+		return "", "", err
+	}
+	return t.ID, userID, nil
+}
+
+// Authenticates the request and opens a session, writing an HTTP error on failure
+func (s *Server) connect(w http.ResponseWriter, r *http.Request) (*Session, []Event, bool) {
+	tenantID, userID, err := s.authenticate(r)
+	if errors.Is(err, auth.ErrUnauthenticated) {
+		http.Error(w, "unauthenticated", http.StatusUnauthorized)
+		return nil, nil, false
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return nil, nil, false
+	}
+
+	session, replay, err := s.hub.Connect(r.Context(), tenantID, userID)
+	if err != nil {
+		s.log.Error("Failed to open realtime session", logging.UserID(userID), zap.Error(err))
+		http.Error(w, "failed to open session", http.StatusServiceUnavailable)
+		return nil, nil, false
+	}
+	return session, replay, true
+}
+
+// Streams events as Server-Sent Events, with the notification ID as event ID
+func (s *Server) serveSSE(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	session, replay, ok := s.connect(w, r)
+	if !ok {
+		return
+	}
+	defer s.hub.Disconnect(session)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	controller := http.NewResponseController(w)
+	send := func(write func() error) error {
+		if err := controller.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		if err := write(); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+
+	err := s.stream(r.Context(), session, replay,
+		func(e Event) error {
+			return send(func() error {
+				data, err := json.Marshal(e)
+				if err != nil {
+					return err
+				}
+				_, err = fmt.Fprintf(w, "id: %d\nevent: notification\ndata: %s\n\n", e.ID, data)
+				return err
+			})
+		},
+		func() error {
+			return send(func() error {
+				_, err := io.WriteString(w, ": ping\n\n")
+				return err
+			})
+		},
+	)
+	if err != nil {
+		s.log.Info("Realtime SSE session ended", logging.UserID(session.UserID), zap.Error(err))
+	}
+}
+
+// Rejects WebSockets opened by pages of other sites, which would otherwise
+// ride on the user's cookies or a token leaked to them. Clients that send
+// no Origin are not browsers.
+func (s *Server) checkOrigin(r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return fmt.Errorf("invalid origin %q", origin)
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return nil
+	}
+	for _, allowed := range s.origins {
+		if strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return nil
+		}
+	}
+	return fmt.Errorf("origin %s is not allowed", origin)
+}
+
+// Streams events as JSON WebSocket messages
+func (s *Server) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	// Checked before connecting so a refused page doesn't take the replay
+	if err := s.checkOrigin(r); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	session, replay, ok := s.connect(w, r)
+	if !ok {
+		return
+	}
+	defer s.hub.Disconnect(session)
+
+	websocket.Server{
+		Handshake: func(_ *websocket.Config, r *http.Request) error {
+			return s.checkOrigin(r)
+		},
+		Handler: func(ws *websocket.Conn) {
+			ctx, cancel := context.WithCancel(r.Context())
+			defer cancel()
+
+			// Nothing is expected from the client; reading notices when it goes away
+			go func() {
+				_, _ = io.Copy(io.Discard, ws)
+				cancel()
+			}()
+
+			send := func(m message) error {
+				if err := ws.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+					return err
+				}
+				return websocket.JSON.Send(ws, m)
+			}
+
+			err := s.stream(ctx, session, replay,
+				func(e Event) error {
+					return send(message{Type: "notification", Notification: &e})
+				},
+				func() error {
+					return send(message{Type: "ping"})
+				},
+			)
+			if err != nil {
+				s.log.Info("Realtime WebSocket session ended", logging.UserID(session.UserID), zap.Error(err))
+			}
+		},
+	}.ServeHTTP(w, r)
+}
+
+// Sends the replayed events, then live ones with heartbeats in between,
+// until the client leaves or the hub drops the session. Events that failed
+// to send are handed back to the session, which keeps them on Disconnect.
+func (s *Server) stream(
+	ctx context.Context,
+	session *Session,
+	replay []Event,
+	sendEvent func(Event) error,
+	sendPing func() error,
+) error {
+	for i, e := range replay {
+		if err := sendEvent(e); err != nil {
+			// The failed event may not have arrived either
+			session.Unsent(replay[i:]...)
+			return err
+		}
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-session.Done():
+			return errors.New("session fell behind")
+		case e := <-session.Events():
+			if err := sendEvent(e); err != nil {
+				session.Unsent(e)
+				return err
+			}
+		case <-heartbeat.C:
+			if err := sendPing(); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package recipients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+
+	"github.com/officiallysidsingh/go-notify/internal/repository"
+)
+
+// Kinds of contact points a user can register
+const (
+	KindEmail     = "email"
+	KindPhone     = "phone"
+	KindPushTopic = "push_topic"
+	KindDevice    = "device"
+)
+
+// Returned when a user has no verified contact for a channel
+var ErrNoContact = errors.New("no verified contact for user")
+
+// E.164 phone number, e.g. +14155552671
+var phonePattern = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// ntfy topic names
+var topicPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Checks that the address is well-formed for its kind
+func Validate(kind, address string) error {
+	switch kind {
+	case KindEmail:
+		if _, err := mail.ParseAddress(address); err != nil {
+			return fmt.Errorf("invalid email address: %w", err)
+		}
+	case KindPhone:
+		if !phonePattern.MatchString(address) {
+			return errors.New("invalid phone number: must be in E.164 format")
+		}
+	case KindPushTopic:
+		if !topicPattern.MatchString(address) {
+			return errors.New("invalid push topic")
+		}
+	case KindDevice:
+		if address == "" {
+			return errors.New("device token is required")
+		}
+	default:
+		return fmt.Errorf("unknown contact kind: %q", kind)
+	}
+	return nil
+}
+
+// Maps a notification queue to the contact kind its sender delivers to
+func KindForQueue(queueName string) string {
+	switch queueName {
+	case "queue_email":
+		return KindEmail
+	case "queue_sms":
+		return KindPhone
+	case "queue_push":
+		return KindPushTopic
+	default:
+		return ""
+	}
+}
+
+// Resolver looks up delivery addresses for users at send time
+type Resolver struct {
+	db *repository.DB
+}
+
+// Creates a new Resolver
+func NewResolver(db *repository.DB) *Resolver {
+	return &Resolver{db: db}
+}
+
+// Returns the verified addresses of the given kind for a user
+func (r *Resolver) Resolve(ctx context.Context, userID, kind string) ([]string, error) {
+	addresses, err := r.db.GetVerifiedAddresses(ctx, userID, kind)
+	if err != nil {
+		return nil, err
+	}
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("%w: user %s, kind %s", ErrNoContact, userID, kind)
+	}
+	return addresses, nil
+}
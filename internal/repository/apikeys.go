@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// APIKey is a stored client credential; only the key's hash is kept.
+type APIKey struct {
+	ID       int64          `db:"id"`
+	TenantID string         `db:"tenant_id"`
+	Name     string         `db:"name"`
+	Scopes   pq.StringArray `db:"scopes"`
+}
+
+// Stores a new API key by its hash
+func (d *DB) InsertAPIKey(ctx context.Context, tenantID, name, keyHash string, scopes []string) (int64, error) {
+	var id int64
+	query := `
+		INSERT INTO api_keys (tenant_id, name, key_hash, scopes)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id`
+
+	err := d.Conn.QueryRowContext(ctx, query, tenantID, name, keyHash, pq.StringArray(scopes)).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert api key: %w", err)
+	}
+
+	return id, nil
+}
+
+// Returns the unrevoked API key with the given hash
+func (d *DB) GetAPIKeyByHash(ctx context.Context, keyHash string) (*APIKey, error) {
+	var key APIKey
+	query := `
+		SELECT id, tenant_id, name, scopes
+		FROM api_keys
+		WHERE key_hash = $1 AND revoked_at IS NULL`
+
+	if err := d.Conn.GetContext(ctx, &key, query, keyHash); err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	return &key, nil
+}
+
+// Revokes an API key by name
+func (d *DB) RevokeAPIKey(ctx context.Context, tenantID, name string) (int64, error) {
+	query := `
+		UPDATE api_keys SET revoked_at = CURRENT_TIMESTAMP
+		WHERE tenant_id = $1 AND name = $2 AND revoked_at IS NULL`
+
+	result, err := d.Conn.ExecContext(ctx, query, tenantID, name)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	return result.RowsAffected()
+}
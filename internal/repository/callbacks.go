@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/officiallysidsingh/go-notify/internal/tenant"
+)
+
+// Callback is a status event waiting to be POSTed to the calling service.
+type Callback struct {
+	ID             int64  `db:"id"`
+	TenantID       string `db:"tenant_id"`
+	NotificationID int64  `db:"notification_id"`
+	URL            string `db:"url"`
+	Payload        []byte `db:"payload"`
+	Attempts       int    `db:"attempts"`
+}
+
+// CallbackAttempt is the outcome of one POST of a callback.
+type CallbackAttempt struct {
+	StatusCode int // 0 when no response was received
+	Error      string
+	Duration   time.Duration
+}
+
+// How long a claimed callback is hidden from other workers while it is sent
+const callbackLease = time.Minute
+
+// Queues a status event for the context's tenant
+func (d *DB) InsertCallback(ctx context.Context, notificationID int64, url string, payload []byte) error {
+	query := `
+		INSERT INTO callbacks (tenant_id, notification_id, url, payload)
+		VALUES ($1, $2, $3, $4)`
+
+	if _, err := d.Conn.ExecContext(ctx, query, tenant.FromContext(ctx), notificationID, url, payload); err != nil {
+		return fmt.Errorf("failed to insert callback: %w", err)
+	}
+
+	return nil
+}
+
+// Returns pending callbacks of every tenant that are due, leasing them so
+// other workers skip them until they are recorded or the lease runs out
+func (d *DB) ClaimDueCallbacks(ctx context.Context, limit int) ([]Callback, error) {
+	due := []Callback{}
+	query := `
+		UPDATE callbacks
+		SET next_attempt_at = NOW() + $2 * INTERVAL '1 millisecond'
+		WHERE id IN (
+			SELECT id FROM callbacks
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, tenant_id, notification_id, url, payload, attempts`
+
+	if err := d.Conn.SelectContext(ctx, &due, query, limit, callbackLease.Milliseconds()); err != nil {
+		return nil, fmt.Errorf("failed to claim due callbacks: %w", err)
+	}
+
+	return due, nil
+}
+
+// Records an attempt and moves the callback to status ("pending" to retry
+// at nextAttempt, "delivered" or "failed")
+func (d *DB) RecordCallbackAttempt(
+	ctx context.Context,
+	cb Callback,
+	attempt CallbackAttempt,
+	status string,
+	nextAttempt time.Time,
+) (err error) {
+	tx, err := d.Conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	// Ensure rollback if something goes wrong
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				err = fmt.Errorf("rollback error: %v (original error: %w)", rbErr, err)
+			}
+		}
+	}()
+
+	insert := `
+		INSERT INTO callback_attempts (callback_id, attempt, status_code, error, duration_ms)
+		VALUES ($1, $2, NULLIF($3, 0), NULLIF($4, ''), $5)`
+
+	_, err = tx.ExecContext(
+		ctx,
+		insert,
+		cb.ID,
+		cb.Attempts+1,
+		attempt.StatusCode,
+		attempt.Error,
+		attempt.Duration.Milliseconds(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record callback attempt: %w", err)
+	}
+
+	update := `
+		UPDATE callbacks
+		SET status = $2, attempts = attempts + 1, next_attempt_at = $3, last_error = NULLIF($4, '')
+		WHERE id = $1`
+
+	if _, err = tx.ExecContext(ctx, update, cb.ID, status, nextAttempt, attempt.Error); err != nil {
+		return fmt.Errorf("failed to update callback: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/officiallysidsingh/go-notify/internal/tenant"
+)
+
+// Returned when a notification was already sent, failed or cancelled
+var ErrNotCancellable = errors.New("notification can no longer be cancelled")
+
+// Returned when updating a notification the caller has cancelled
+var ErrCancelled = errors.New("notification was cancelled")
+
+// Cancels a notification of the context's tenant that has not been sent yet
+// and returns its resulting status. Returns sql.ErrNoRows if it doesn't exist.
+func (d *DB) CancelNotification(ctx context.Context, id int64) (string, error) {
+	var status string
+	tenantID := tenant.FromContext(ctx)
+
+	query := `
+		UPDATE notifications
+		SET status = 'cancelled', status_reason = 'cancelled by caller', deliver_after = NULL
+		WHERE id = $1 AND tenant_id = $2 AND status IN ('pending', 'retrying', 'deferred', 'digested')
+		RETURNING status`
+
+	err := d.Conn.QueryRowContext(ctx, query, id, tenantID).Scan(&status)
+	if err == nil {
+		return status, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("failed to cancel notification: %w", err)
+	}
+
+	// Tell a missing notification apart from one that is past cancelling
+	current := `SELECT COALESCE(status, '') FROM notifications WHERE id = $1 AND tenant_id = $2`
+	if err := d.Conn.QueryRowContext(ctx, current, id, tenantID).Scan(&status); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", err
+		}
+		return "", fmt.Errorf("failed to get notification status: %w", err)
+	}
+	return status, ErrNotCancellable
+}
+
+// Returns the user and callback URL of a notification of the context's
+// tenant, with "" for the URL when it has none
+func (d *DB) GetNotificationCallback(ctx context.Context, id int64) (userID, callbackURL string, err error) {
+	query := `SELECT user_id, COALESCE(callback_url, '') FROM notifications WHERE id = $1 AND tenant_id = $2`
+
+	if err := d.Conn.QueryRowContext(ctx, query, id, tenant.FromContext(ctx)).Scan(&userID, &callbackURL); err != nil {
+		return "", "", fmt.Errorf("failed to get notification callback: %w", err)
+	}
+
+	return userID, callbackURL, nil
+}
+
+// Reports whether a notification of the context's tenant was cancelled before it was delivered
+func (d *DB) IsNotificationCancelled(ctx context.Context, id int64) (bool, error) {
+	var cancelled bool
+	query := `SELECT status = 'cancelled' FROM notifications WHERE id = $1 AND tenant_id = $2`
+
+	if err := d.Conn.QueryRowContext(ctx, query, id, tenant.FromContext(ctx)).Scan(&cancelled); err != nil {
+		return false, fmt.Errorf("failed to check notification status: %w", err)
+	}
+
+	return cancelled, nil
+}
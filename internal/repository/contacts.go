@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/officiallysidsingh/go-notify/internal/tenant"
+)
+
+// Contact is a single delivery address registered for a user.
+type Contact struct {
+	ID         int64        `db:"id"`
+	UserID     string       `db:"user_id"`
+	Kind       string       `db:"kind"`
+	Address    string       `db:"address"`
+	Label      string       `db:"label"`
+	Verified   bool         `db:"verified"`
+	VerifiedAt sql.NullTime `db:"verified_at"`
+	CreatedAt  time.Time    `db:"created_at"`
+	UpdatedAt  time.Time    `db:"updated_at"`
+}
+
+const contactColumns = `id, user_id, kind, address, label, verified, verified_at, created_at, updated_at`
+
+// Inserts a new contact point for a user of the context's tenant
+func (d *DB) InsertContact(ctx context.Context, userID, kind, address, label string) (*Contact, error) {
+	var contact Contact
+	query := `
+		INSERT INTO user_contacts (user_id, kind, address, label, tenant_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + contactColumns
+
+	err := d.Conn.GetContext(ctx, &contact, query, userID, kind, address, label, tenant.FromContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert contact: %w", err)
+	}
+
+	return &contact, nil
+}
+
+// Returns a single contact of the context's tenant by ID
+func (d *DB) GetContact(ctx context.Context, id int64) (*Contact, error) {
+	var contact Contact
+	query := `SELECT ` + contactColumns + ` FROM user_contacts WHERE id = $1 AND tenant_id = $2`
+
+	if err := d.Conn.GetContext(ctx, &contact, query, id, tenant.FromContext(ctx)); err != nil {
+		return nil, fmt.Errorf("failed to get contact: %w", err)
+	}
+
+	return &contact, nil
+}
+
+// Returns all contacts of a user, optionally filtered by kind
+func (d *DB) ListContacts(ctx context.Context, userID, kind string) ([]Contact, error) {
+	contacts := []Contact{}
+	query := `
+		SELECT ` + contactColumns + `
+		FROM user_contacts
+		WHERE tenant_id = $3 AND user_id = $1 AND ($2 = '' OR kind = $2)
+		ORDER BY created_at, id`
+
+	if err := d.Conn.SelectContext(ctx, &contacts, query, userID, kind, tenant.FromContext(ctx)); err != nil {
+		return nil, fmt.Errorf("failed to list contacts: %w", err)
+	}
+
+	return contacts, nil
+}
+
+// Returns the verified addresses of a given kind, oldest first
+func (d *DB) GetVerifiedAddresses(ctx context.Context, userID, kind string) ([]string, error) {
+	addresses := []string{}
+	query := `
+		SELECT address
+		FROM user_contacts
+		WHERE tenant_id = $3 AND user_id = $1 AND kind = $2 AND verified
+		ORDER BY created_at, id`
+
+	if err := d.Conn.SelectContext(ctx, &addresses, query, userID, kind, tenant.FromContext(ctx)); err != nil {
+		return nil, fmt.Errorf("failed to get verified addresses: %w", err)
+	}
+
+	return addresses, nil
+}
+
+// Updates a contact's address, label and verification state.
+// Changing the address always clears verification.
+func (d *DB) UpdateContact(ctx context.Context, contact *Contact) (*Contact, error) {
+	var updated Contact
+	query := `
+		UPDATE user_contacts
+		SET label = $2,
+			verified = $3 AND address = $4,
+			verified_at = CASE
+				WHEN NOT ($3 AND address = $4) THEN NULL
+				WHEN verified THEN verified_at
+				ELSE CURRENT_TIMESTAMP
+			END,
+			address = $4,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND tenant_id = $5
+		RETURNING ` + contactColumns
+
+	err := d.Conn.GetContext(
+		ctx,
+		&updated,
+		query,
+		contact.ID,
+		contact.Label,
+		contact.Verified,
+		contact.Address,
+		tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update contact: %w", err)
+	}
+
+	return &updated, nil
+}
+
+// Removes a contact
+func (d *DB) DeleteContact(ctx context.Context, id int64) error {
+	query := `DELETE FROM user_contacts WHERE id = $1 AND tenant_id = $2`
+
+	result, err := d.Conn.ExecContext(ctx, query, id, tenant.FromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to delete contact: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
@@ -8,23 +8,30 @@ import (
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 	"github.com/officiallysidsingh/go-notify/config"
+	"github.com/officiallysidsingh/go-notify/internal/logging"
+	"github.com/officiallysidsingh/go-notify/internal/tenant"
+	"github.com/uptrace/opentelemetry-go-extra/otelsql"
+	"go.uber.org/zap"
 )
 
 type DB struct {
 	Conn *sqlx.DB
+	log  *zap.Logger
 }
 
 // Creates a new database connection
-func NewDB(cfg config.PostgresConfig) (*DB, error) {
+func NewDB(cfg config.PostgresConfig, logger *zap.Logger) (*DB, error) {
 	// Create a context with timeout for establishing the connection.
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.ConnTimeout)
 	defer cancel()
 
-	// Open a database connection using sqlx.
-	db, err := sqlx.Open("postgres", cfg.DataSourceName)
+	// Open a database connection, tracing every query as a span of the
+	// caller's context.
+	sqlDB, err := otelsql.Open("postgres", cfg.DataSourceName, otelsql.WithDBSystem("postgresql"))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open DB: %w", err)
 	}
+	db := sqlx.NewDb(sqlDB, "postgres")
 
 	// Ping the database to ensure a successful connection.
 	if err := db.PingContext(ctx); err != nil {
@@ -37,7 +44,9 @@ func NewDB(cfg config.PostgresConfig) (*DB, error) {
 	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
 	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 
-	return &DB{Conn: db}, nil
+	logger.Info("Connected to Postgres", zap.Int("max_open_conns", cfg.MaxOpenConns))
+
+	return &DB{Conn: db, log: logger}, nil
 }
 
 // Close gracefully closes the database connection.
@@ -45,13 +54,23 @@ func (d *DB) Close() error {
 	return d.Conn.Close()
 }
 
-// Inserts a new notification into the database and returns its generated ID
+// NewNotification holds the fields stored when a notification is created
+type NewNotification struct {
+	UserID   string
+	Caller   string // Client that sent the request
+	Type     string // Delivery channel (email, sms, push)
+	Category string
+	Message  string
+	Status   string
+
+	CallbackURL string // Receives status events, "" for none
+}
+
+// Inserts a new notification for the context's tenant and returns its generated ID
 func (d *DB) InsertNotification(
 	ctx context.Context,
-	userID, message, status string,
-) (int64, error) {
-	var id int64
-
+	n NewNotification,
+) (id int64, err error) {
 	// Begin a transaction
 	tx, err := d.Conn.BeginTxx(ctx, nil)
 	if err != nil {
@@ -67,30 +86,69 @@ func (d *DB) InsertNotification(
 		}
 	}()
 
+	if id, err = d.insertNotification(ctx, tx, n); err != nil {
+		return 0, err
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return id, nil
+}
+
+// Inserts a notification within tx
+func (d *DB) insertNotification(ctx context.Context, tx *sqlx.Tx, n NewNotification) (int64, error) {
+	var id int64
 	query := `
-		INSERT INTO notifications (user_id, message, status) 
-		VALUES ($1, $2, $3) 
+		INSERT INTO notifications (user_id, caller, type, category, message, status, tenant_id, callback_url) 
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), NULLIF($4, ''), $5, $6, $7, NULLIF($8, '')) 
 		RETURNING id`
 
-	err = tx.QueryRowContext(ctx, query, userID, message, status).Scan(&id)
+	err := tx.QueryRowContext(
+		ctx,
+		query,
+		n.UserID,
+		n.Caller,
+		n.Type,
+		n.Category,
+		n.Message,
+		n.Status,
+		tenant.FromContext(ctx),
+		n.CallbackURL,
+	).Scan(&id)
 	if err != nil {
 		return 0, fmt.Errorf("failed to insert notification: %w", err)
 	}
 
-	// Commit transaction
-	if err = tx.Commit(); err != nil {
-		return 0, fmt.Errorf("failed to commit transaction: %w", err)
-	}
+	logging.FromContext(ctx, d.log).Debug(
+		"Inserted notification",
+		logging.NotificationID(id),
+		logging.UserID(n.UserID),
+		logging.Channel(n.Type),
+		zap.String("status", n.Status),
+	)
 
 	return id, nil
 }
 
-// updates the status of a notification
+// updates the status of a notification of the context's tenant
 func (d *DB) UpdateNotificationStatus(ctx context.Context, id int64, status string) error {
-	query := `UPDATE notifications SET status = $1 WHERE id = $2`
+	return d.UpdateNotificationStatusWithReason(ctx, id, status, "")
+}
+
+// updates the status of a notification of the context's tenant along with
+// why it has that status. Cancelled notifications keep their status and
+// return ErrCancelled.
+func (d *DB) UpdateNotificationStatusWithReason(ctx context.Context, id int64, status, reason string) error {
+	query := `
+		UPDATE notifications
+		SET status = $1, status_reason = NULLIF($2, '')
+		WHERE id = $3 AND tenant_id = $4 AND status IS DISTINCT FROM 'cancelled'`
 
 	// ExecContext is used for executing queries with a context.
-	result, err := d.Conn.ExecContext(ctx, query, status, id)
+	result, err := d.Conn.ExecContext(ctx, query, status, reason, id, tenant.FromContext(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to update notification status: %w", err)
 	}
@@ -101,8 +159,47 @@ func (d *DB) UpdateNotificationStatus(ctx context.Context, id int64, status stri
 		return fmt.Errorf("failed to get rows affected: %w", err)
 	}
 	if rowsAffected == 0 {
+		// Tell a cancelled notification apart from a missing one
+		cancelled, err := d.IsNotificationCancelled(ctx, id)
+		if err == nil && cancelled {
+			return ErrCancelled
+		}
 		return sql.ErrNoRows
 	}
 
+	logging.FromContext(ctx, d.log).Debug(
+		"Updated notification status",
+		logging.NotificationID(id),
+		zap.String("status", status),
+		zap.String("reason", reason),
+	)
+
 	return nil
 }
+
+// NotificationStatus is how far a notification has got
+type NotificationStatus struct {
+	Status string
+	Reason string
+	ReadAt sql.NullTime // Set once an in-app notification is read
+}
+
+// Returns the status of a notification of the context's tenant
+func (d *DB) GetNotificationStatus(ctx context.Context, id int64) (NotificationStatus, error) {
+	var row struct {
+		Status       sql.NullString `db:"status"`
+		StatusReason sql.NullString `db:"status_reason"`
+		ReadAt       sql.NullTime   `db:"read_at"`
+	}
+	query := `SELECT status, status_reason, read_at FROM notifications WHERE id = $1 AND tenant_id = $2`
+
+	if err := d.Conn.GetContext(ctx, &row, query, id, tenant.FromContext(ctx)); err != nil {
+		return NotificationStatus{}, fmt.Errorf("failed to get notification status: %w", err)
+	}
+
+	return NotificationStatus{
+		Status: row.Status.String,
+		Reason: row.StatusReason.String,
+		ReadAt: row.ReadAt,
+	}, nil
+}
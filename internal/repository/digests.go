@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/officiallysidsingh/go-notify/internal/tenant"
+)
+
+// Digest accumulates notifications for one user, channel and key.
+type Digest struct {
+	ID        int64  `db:"id"`
+	TenantID  string `db:"tenant_id"`
+	UserID    string `db:"user_id"`
+	Channel   string `db:"channel"`
+	DigestKey string `db:"digest_key"`
+	Attempts  int    `db:"attempts"` // Claims so far, including this one
+
+	// Summary notification created by an earlier attempt, reused on retry
+	NotificationID sql.NullInt64 `db:"notification_id"`
+}
+
+// DigestItem is a notification collected into a digest.
+type DigestItem struct {
+	ID      int64  `db:"id"`
+	Payload []byte `db:"payload"`
+}
+
+// Adds a notification to the open digest for its tenant, user, channel and key,
+// opening a new digest that closes at windowEnd if none exists
+func (d *DB) AddToDigest(
+	ctx context.Context,
+	notificationID int64,
+	userID, channel, key string,
+	windowEnd time.Time,
+	payload []byte,
+) (digestID int64, err error) {
+	tx, err := d.Conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	// Ensure rollback if something goes wrong
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				err = fmt.Errorf("rollback error: %v (original error: %w)", rbErr, err)
+			}
+		}
+	}()
+
+	// The no-op update locks the open digest so it can't be flushed mid-insert
+	upsert := `
+		INSERT INTO digests (tenant_id, user_id, channel, digest_key, window_end)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (tenant_id, user_id, channel, digest_key) WHERE status = 'open'
+		DO UPDATE SET window_end = digests.window_end
+		RETURNING id`
+
+	err = tx.QueryRowContext(ctx, upsert, tenant.FromContext(ctx), userID, channel, key, windowEnd).Scan(&digestID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open digest: %w", err)
+	}
+
+	link := `
+		UPDATE notifications
+		SET status = 'digested', status_reason = $3, digest_id = $2, payload = $4
+		WHERE id = $1 AND tenant_id = $5`
+
+	reason := fmt.Sprintf("collected into digest %d", digestID)
+	if _, err = tx.ExecContext(ctx, link, notificationID, digestID, reason, payload, tenant.FromContext(ctx)); err != nil {
+		return 0, fmt.Errorf("failed to link notification to digest: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return digestID, nil
+}
+
+// How long a claimed digest is left to its worker before others retry it
+const digestLease = time.Minute
+
+// Closes open digests of every tenant whose window has ended and returns them,
+// along with digests whose earlier claim ran out without being completed
+func (d *DB) ClaimDueDigests(ctx context.Context, limit int) ([]Digest, error) {
+	due := []Digest{}
+	query := `
+		UPDATE digests
+		SET status = 'sending', claimed_at = NOW(), attempts = attempts + 1
+		WHERE id IN (
+			SELECT id FROM digests
+			WHERE (status = 'open' AND window_end <= NOW())
+				OR (status = 'sending' AND claimed_at <= NOW() - $2 * INTERVAL '1 millisecond')
+			ORDER BY window_end
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, tenant_id, user_id, channel, digest_key, attempts`
+
+	if err := d.Conn.SelectContext(ctx, &due, query, limit, digestLease.Milliseconds()); err != nil {
+		return nil, fmt.Errorf("failed to claim due digests: %w", err)
+	}
+
+	return due, nil
+}
+
+// Creates the summary notification of a digest in the context's tenant and
+// records it on the digest, so a retried send reuses it instead of creating another
+func (d *DB) InsertDigestSummary(ctx context.Context, digestID int64, n NewNotification) (id int64, err error) {
+	tx, err := d.Conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	// Ensure rollback if something goes wrong
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				err = fmt.Errorf("rollback error: %v (original error: %w)", rbErr, err)
+			}
+		}
+	}()
+
+	if id, err = d.insertNotification(ctx, tx, n); err != nil {
+		return 0, err
+	}
+
+	if _, err = tx.ExecContext(ctx, `UPDATE digests SET notification_id = $2 WHERE id = $1`, digestID, id); err != nil {
+		return 0, fmt.Errorf("failed to record digest summary: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return id, nil
+}
+
+// Returns the notifications collected into a digest, oldest first,
+// leaving out any that were cancelled
+func (d *DB) GetDigestItems(ctx context.Context, digestID int64) ([]DigestItem, error) {
+	items := []DigestItem{}
+	query := `
+		SELECT id, payload
+		FROM notifications
+		WHERE digest_id = $1 AND status = 'digested'
+		ORDER BY id`
+
+	if err := d.Conn.SelectContext(ctx, &items, query, digestID); err != nil {
+		return nil, fmt.Errorf("failed to get digest items: %w", err)
+	}
+
+	return items, nil
+}
+
+// Records the outcome of a digest and the notification that delivered it
+func (d *DB) CompleteDigest(ctx context.Context, digestID, notificationID int64, status string) error {
+	query := `UPDATE digests SET status = $2, notification_id = NULLIF($3, 0) WHERE id = $1`
+
+	if _, err := d.Conn.ExecContext(ctx, query, digestID, status, notificationID); err != nil {
+		return fmt.Errorf("failed to complete digest: %w", err)
+	}
+
+	return nil
+}
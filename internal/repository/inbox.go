@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/officiallysidsingh/go-notify/internal/tenant"
+)
+
+// InboxItem is an in-app notification in a user's inbox
+type InboxItem struct {
+	NotificationID int64          `db:"notification_id"`
+	UserID         string         `db:"user_id"`
+	Title          string         `db:"title"`
+	Message        string         `db:"message"`
+	Category       sql.NullString `db:"category"`
+	Priority       sql.NullString `db:"priority"`
+	CreatedAt      time.Time      `db:"created_at"`
+	ReadAt         sql.NullTime   `db:"read_at"`
+	ArchivedAt     sql.NullTime   `db:"archived_at"`
+}
+
+// InboxFilter selects one page of a user's inbox, newest first
+type InboxFilter struct {
+	UnreadOnly bool
+	Archived   bool // Archived items instead of the current ones
+	After      *NotificationCursor
+	Limit      int
+}
+
+// Adds an item to its user's inbox in the context's tenant and marks its
+// notification sent in the same transaction, so a read that lands right
+// after can't be overwritten. Redelivering the same notification leaves the
+// existing item, and a "read" status, as they are. Returns ErrCancelled
+// without storing the item if the notification was cancelled.
+func (d *DB) InsertInboxItem(ctx context.Context, item InboxItem) (err error) {
+	tx, err := d.Conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	// Ensure rollback if something goes wrong
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				err = fmt.Errorf("rollback error: %v (original error: %w)", rbErr, err)
+			}
+		}
+	}()
+
+	// Locks the notification so marking it read waits for this transaction
+	var status sql.NullString
+	lock := `SELECT status FROM notifications WHERE id = $1 AND tenant_id = $2 FOR UPDATE`
+	if err = tx.QueryRowContext(ctx, lock, item.NotificationID, tenant.FromContext(ctx)).Scan(&status); err != nil {
+		return fmt.Errorf("failed to lock notification: %w", err)
+	}
+	if status.String == "cancelled" {
+		return ErrCancelled
+	}
+
+	insert := `
+		INSERT INTO inbox_items (notification_id, tenant_id, user_id, title, message, category, priority)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (notification_id) DO NOTHING`
+
+	_, err = tx.ExecContext(
+		ctx,
+		insert,
+		item.NotificationID,
+		tenant.FromContext(ctx),
+		item.UserID,
+		item.Title,
+		item.Message,
+		item.Category,
+		item.Priority,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert inbox item: %w", err)
+	}
+
+	if status.String != "read" {
+		sent := `UPDATE notifications SET status = 'sent', status_reason = NULL WHERE id = $1`
+		if _, err = tx.ExecContext(ctx, sent, item.NotificationID); err != nil {
+			return fmt.Errorf("failed to update notification status: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Returns one page of a user's inbox
+func (d *DB) ListInbox(ctx context.Context, userID string, f InboxFilter) ([]InboxItem, error) {
+	query := `
+		SELECT notification_id, user_id, title, message, category, priority, created_at, read_at, archived_at
+		FROM inbox_items
+		WHERE tenant_id = $1 AND user_id = $2
+			AND (archived_at IS NOT NULL) = $3
+			AND (NOT $4 OR read_at IS NULL)
+			AND ($6::BIGINT IS NULL OR (created_at, notification_id) < ($5::TIMESTAMP, $6::BIGINT))
+		ORDER BY created_at DESC, notification_id DESC
+		LIMIT $7`
+
+	var (
+		afterAt sql.NullTime
+		afterID sql.NullInt64
+	)
+	if f.After != nil {
+		afterAt = sql.NullTime{Time: f.After.CreatedAt, Valid: true}
+		afterID = sql.NullInt64{Int64: f.After.ID, Valid: true}
+	}
+
+	items := []InboxItem{}
+	err := d.Conn.SelectContext(
+		ctx,
+		&items,
+		query,
+		tenant.FromContext(ctx),
+		userID,
+		f.Archived,
+		f.UnreadOnly,
+		afterAt,
+		afterID,
+		f.Limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list inbox: %w", err)
+	}
+
+	return items, nil
+}
+
+// Counts a user's unread, unarchived inbox items by category
+func (d *DB) CountUnread(ctx context.Context, userID string) (map[string]int64, error) {
+	var rows []struct {
+		Category string `db:"category"`
+		Count    int64  `db:"count"`
+	}
+	query := `
+		SELECT COALESCE(category, 'default') AS category, COUNT(*) AS count
+		FROM inbox_items
+		WHERE tenant_id = $1 AND user_id = $2 AND read_at IS NULL AND archived_at IS NULL
+		GROUP BY 1`
+
+	if err := d.Conn.SelectContext(ctx, &rows, query, tenant.FromContext(ctx), userID); err != nil {
+		return nil, fmt.Errorf("failed to count unread inbox items: %w", err)
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, r := range rows {
+		counts[r.Category] = r.Count
+	}
+	return counts, nil
+}
+
+// Marks a user's unread inbox items as read, or all of them when ids is nil,
+// and moves their notifications to status "read" with the same timestamp.
+// Returns how many items were marked.
+func (d *DB) MarkInboxRead(ctx context.Context, userID string, ids []int64) (int64, error) {
+	query := `
+		WITH marked AS (
+			UPDATE inbox_items
+			SET read_at = CURRENT_TIMESTAMP
+			WHERE tenant_id = $1 AND user_id = $2 AND read_at IS NULL
+				AND ($3::BIGINT[] IS NULL OR notification_id = ANY($3))
+			RETURNING notification_id, read_at
+		)
+		UPDATE notifications n
+		SET status = 'read', status_reason = NULL, read_at = marked.read_at
+		FROM marked
+		WHERE n.id = marked.notification_id`
+
+	res, err := d.Conn.ExecContext(ctx, query, tenant.FromContext(ctx), userID, pq.Int64Array(ids))
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark inbox items read: %w", err)
+	}
+
+	return res.RowsAffected()
+}
+
+// Moves a user's inbox items to the archive. Returns how many were archived.
+func (d *DB) ArchiveInboxItems(ctx context.Context, userID string, ids []int64) (int64, error) {
+	query := `
+		UPDATE inbox_items
+		SET archived_at = CURRENT_TIMESTAMP
+		WHERE tenant_id = $1 AND user_id = $2 AND archived_at IS NULL
+			AND notification_id = ANY($3)`
+
+	res, err := d.Conn.ExecContext(ctx, query, tenant.FromContext(ctx), userID, pq.Int64Array(ids))
+	if err != nil {
+		return 0, fmt.Errorf("failed to archive inbox items: %w", err)
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/officiallysidsingh/go-notify/internal/tenant"
+)
+
+// Returns the user's preferred locale, or "" if none is set
+func (d *DB) GetUserLocale(ctx context.Context, userID string) (string, error) {
+	var locale string
+	query := `SELECT locale FROM user_settings WHERE tenant_id = $1 AND user_id = $2`
+
+	err := d.Conn.GetContext(ctx, &locale, query, tenant.FromContext(ctx), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get user locale: %w", err)
+	}
+
+	return locale, nil
+}
+
+// Creates or updates the user's preferred locale
+func (d *DB) SetUserLocale(ctx context.Context, userID, locale string) error {
+	query := `
+		INSERT INTO user_settings (tenant_id, user_id, locale, updated_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+		ON CONFLICT (tenant_id, user_id)
+		DO UPDATE SET locale = EXCLUDED.locale, updated_at = CURRENT_TIMESTAMP`
+
+	if _, err := d.Conn.ExecContext(ctx, query, tenant.FromContext(ctx), userID, locale); err != nil {
+		return fmt.Errorf("failed to set user locale: %w", err)
+	}
+
+	return nil
+}
+
+// Records the locale that was chosen when sending a notification of the context's tenant
+func (d *DB) UpdateNotificationLocale(ctx context.Context, id int64, locale string) error {
+	query := `UPDATE notifications SET locale = $1 WHERE id = $2 AND tenant_id = $3`
+
+	if _, err := d.Conn.ExecContext(ctx, query, locale, id, tenant.FromContext(ctx)); err != nil {
+		return fmt.Errorf("failed to update notification locale: %w", err)
+	}
+
+	return nil
+}
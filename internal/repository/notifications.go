@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/officiallysidsingh/go-notify/internal/tenant"
+)
+
+// NotificationRecord is a stored notification as listed to support staff
+type NotificationRecord struct {
+	ID           int64          `db:"id"`
+	UserID       string         `db:"user_id"`
+	Type         sql.NullString `db:"type"`
+	Category     sql.NullString `db:"category"`
+	Message      string         `db:"message"`
+	Status       sql.NullString `db:"status"`
+	StatusReason sql.NullString `db:"status_reason"`
+	Caller       sql.NullString `db:"caller"`
+	CreatedAt    time.Time      `db:"created_at"`
+	ReadAt       sql.NullTime   `db:"read_at"`
+}
+
+// NotificationCursor is the position after which the next page starts
+type NotificationCursor struct {
+	CreatedAt time.Time
+	ID        int64
+}
+
+// NotificationFilter narrows and orders ListNotifications. Empty fields match everything.
+type NotificationFilter struct {
+	UserID        string
+	Status        string
+	Type          string
+	CreatedAfter  time.Time // Inclusive, zero for no bound
+	CreatedBefore time.Time // Exclusive, zero for no bound
+	Ascending     bool      // Oldest first instead of newest first
+	After         *NotificationCursor
+	Limit         int
+}
+
+// Returns the context tenant's notifications matching the filter, ordered by
+// creation time and ID so pages never skip or repeat rows
+func (d *DB) ListNotifications(ctx context.Context, f NotificationFilter) ([]NotificationRecord, error) {
+	// Only these two fixed fragments are ever formatted into the query
+	cmp, dir := "<", "DESC"
+	if f.Ascending {
+		cmp, dir = ">", "ASC"
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, user_id, type, category, message, status, status_reason, caller, created_at, read_at
+		FROM notifications
+		WHERE tenant_id = $1
+			AND ($2 = '' OR user_id = $2)
+			AND ($3 = '' OR status = $3)
+			AND ($4 = '' OR type = $4)
+			AND ($5::TIMESTAMP IS NULL OR created_at >= $5::TIMESTAMP)
+			AND ($6::TIMESTAMP IS NULL OR created_at < $6::TIMESTAMP)
+			AND ($8::BIGINT IS NULL OR (created_at, id) %s ($7::TIMESTAMP, $8::BIGINT))
+		ORDER BY created_at %s, id %s
+		LIMIT $9`, cmp, dir, dir)
+
+	var (
+		afterAt sql.NullTime
+		afterID sql.NullInt64
+	)
+	if f.After != nil {
+		afterAt = sql.NullTime{Time: f.After.CreatedAt.UTC(), Valid: true}
+		afterID = sql.NullInt64{Int64: f.After.ID, Valid: true}
+	}
+
+	records := []NotificationRecord{}
+	err := d.Conn.SelectContext(
+		ctx,
+		&records,
+		query,
+		tenant.FromContext(ctx),
+		f.UserID,
+		f.Status,
+		f.Type,
+		nullTime(f.CreatedAfter),
+		nullTime(f.CreatedBefore),
+		afterAt,
+		afterID,
+		f.Limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+
+	return records, nil
+}
+
+// Maps the zero time to NULL. created_at has no time zone and holds UTC, and
+// casting to TIMESTAMP drops the offset, so bounds are converted to UTC first.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t.UTC(), Valid: !t.IsZero()}
+}
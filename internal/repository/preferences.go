@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/officiallysidsingh/go-notify/internal/tenant"
+)
+
+// Matches any channel or category in a preference
+const PreferenceWildcard = "*"
+
+// Preference enables or disables one channel/category pair for a user.
+type Preference struct {
+	Channel  string `db:"channel"`
+	Category string `db:"category"`
+	Enabled  bool   `db:"enabled"`
+}
+
+// Returns all preferences stored for a user
+func (d *DB) GetPreferences(ctx context.Context, userID string) ([]Preference, error) {
+	prefs := []Preference{}
+	query := `
+		SELECT channel, category, enabled
+		FROM user_preferences
+		WHERE tenant_id = $1 AND user_id = $2
+		ORDER BY channel, category`
+
+	if err := d.Conn.SelectContext(ctx, &prefs, query, tenant.FromContext(ctx), userID); err != nil {
+		return nil, fmt.Errorf("failed to get preferences: %w", err)
+	}
+
+	return prefs, nil
+}
+
+// Creates or updates preferences for a user in a single transaction
+func (d *DB) UpsertPreferences(ctx context.Context, userID string, prefs []Preference) (err error) {
+	tx, err := d.Conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	// Ensure rollback if something goes wrong
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				err = fmt.Errorf("rollback error: %v (original error: %w)", rbErr, err)
+			}
+		}
+	}()
+
+	query := `
+		INSERT INTO user_preferences (tenant_id, user_id, channel, category, enabled, updated_at)
+		VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP)
+		ON CONFLICT (tenant_id, user_id, channel, category)
+		DO UPDATE SET enabled = EXCLUDED.enabled, updated_at = CURRENT_TIMESTAMP`
+
+	tenantID := tenant.FromContext(ctx)
+	for _, pref := range prefs {
+		if _, err = tx.ExecContext(ctx, query, tenantID, userID, pref.Channel, pref.Category, pref.Enabled); err != nil {
+			return fmt.Errorf("failed to upsert preference: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Reports whether the user accepts notifications on a channel for a category.
+// The most specific matching preference wins; with none, delivery is allowed.
+func (d *DB) IsChannelEnabled(ctx context.Context, userID, channel, category string) (bool, error) {
+	var enabled bool
+	query := `
+		SELECT enabled
+		FROM user_preferences
+		WHERE tenant_id = $5 AND user_id = $1
+			AND channel IN ($2, $4)
+			AND category IN ($3, $4)
+		ORDER BY channel = $4, category = $4
+		LIMIT 1`
+
+	err := d.Conn.GetContext(ctx, &enabled, query, userID, channel, category, PreferenceWildcard, tenant.FromContext(ctx))
+	if errors.Is(err, sql.ErrNoRows) {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check preferences: %w", err)
+	}
+
+	return enabled, nil
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/officiallysidsingh/go-notify/internal/tenant"
+)
+
+// QuietHours holds a user's timezone and do-not-disturb window.
+// Start and End are "HH:MM" and empty when quiet hours are disabled.
+type QuietHours struct {
+	Timezone string `db:"timezone"`
+	Start    string `db:"quiet_start"`
+	End      string `db:"quiet_end"`
+}
+
+// DeferredNotification is a notification whose delivery time has come.
+type DeferredNotification struct {
+	ID         int64     `db:"id"`
+	TenantID   string    `db:"tenant_id"`
+	Payload    []byte    `db:"payload"`
+	LeaseUntil time.Time `db:"deliver_after"` // When it is claimed again unless marked republished
+}
+
+// Returns the user's quiet hours, defaulting to UTC with no window
+func (d *DB) GetQuietHours(ctx context.Context, userID string) (QuietHours, error) {
+	quiet := QuietHours{Timezone: "UTC"}
+	query := `
+		SELECT timezone,
+			COALESCE(to_char(quiet_start, 'HH24:MI'), '') AS quiet_start,
+			COALESCE(to_char(quiet_end, 'HH24:MI'), '') AS quiet_end
+		FROM user_settings
+		WHERE tenant_id = $1 AND user_id = $2`
+
+	err := d.Conn.GetContext(ctx, &quiet, query, tenant.FromContext(ctx), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return quiet, nil
+	}
+	if err != nil {
+		return quiet, fmt.Errorf("failed to get quiet hours: %w", err)
+	}
+
+	return quiet, nil
+}
+
+// Creates or updates the user's timezone and quiet hours
+func (d *DB) SetQuietHours(ctx context.Context, userID string, quiet QuietHours) error {
+	query := `
+		INSERT INTO user_settings (user_id, timezone, quiet_start, quiet_end, tenant_id, updated_at)
+		VALUES ($1, $2, NULLIF($3, '')::TIME, NULLIF($4, '')::TIME, $5, CURRENT_TIMESTAMP)
+		ON CONFLICT (tenant_id, user_id)
+		DO UPDATE SET
+			timezone = EXCLUDED.timezone,
+			quiet_start = EXCLUDED.quiet_start,
+			quiet_end = EXCLUDED.quiet_end,
+			updated_at = CURRENT_TIMESTAMP`
+
+	_, err := d.Conn.ExecContext(ctx, query, userID, quiet.Timezone, quiet.Start, quiet.End, tenant.FromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to set quiet hours: %w", err)
+	}
+
+	return nil
+}
+
+// Parks a notification of the context's tenant until the given time, keeping its queue payload
+func (d *DB) DeferNotification(
+	ctx context.Context,
+	id int64,
+	until time.Time,
+	payload []byte,
+	reason string,
+) error {
+	query := `
+		UPDATE notifications
+		SET status = 'deferred', status_reason = $2, deliver_after = $3, payload = $4
+		WHERE id = $1 AND tenant_id = $5 AND status IS DISTINCT FROM 'cancelled'`
+
+	result, err := d.Conn.ExecContext(ctx, query, id, reason, until, payload, tenant.FromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to defer notification: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// How long a claimed deferred notification is left to its worker to publish
+// before others claim it again
+const republishLease = time.Minute
+
+// Claims deferred notifications that are due and returns them. They stay
+// deferred, with deliver_after pushed out by the lease, until MarkRepublished,
+// so a worker dying before it publishes them doesn't strand them.
+// Rows locked by another worker are skipped so replicas don't double-publish.
+func (d *DB) ClaimDueNotifications(ctx context.Context, limit int) ([]DeferredNotification, error) {
+	due := []DeferredNotification{}
+	query := `
+		UPDATE notifications
+		SET deliver_after = NOW() + $2 * INTERVAL '1 millisecond'
+		WHERE id IN (
+			SELECT id FROM notifications
+			WHERE status = 'deferred' AND deliver_after <= NOW()
+			ORDER BY deliver_after
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, tenant_id, payload, deliver_after`
+
+	if err := d.Conn.SelectContext(ctx, &due, query, limit, republishLease.Milliseconds()); err != nil {
+		return nil, fmt.Errorf("failed to claim due notifications: %w", err)
+	}
+
+	return due, nil
+}
+
+// Moves a claimed notification of the context's tenant back to pending once
+// it is on its queue again. Left as it is if the worker already got to it,
+// including deferring it anew.
+func (d *DB) MarkRepublished(ctx context.Context, n DeferredNotification) error {
+	query := `
+		UPDATE notifications
+		SET status = 'pending', status_reason = NULL, deliver_after = NULL
+		WHERE id = $1 AND tenant_id = $2 AND status = 'deferred' AND deliver_after = $3`
+
+	if _, err := d.Conn.ExecContext(ctx, query, n.ID, tenant.FromContext(ctx), n.LeaseUntil); err != nil {
+		return fmt.Errorf("failed to mark notification republished: %w", err)
+	}
+
+	return nil
+}
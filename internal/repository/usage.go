@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/officiallysidsingh/go-notify/internal/tenant"
+)
+
+// QuotaUsage is a caller's usage of one channel after a quota check
+type QuotaUsage struct {
+	Allowed bool
+	Daily   int
+	Monthly int
+}
+
+// UsageRecord counts notifications for one caller, channel and status
+type UsageRecord struct {
+	Caller  string `db:"caller"`
+	Channel string `db:"channel"`
+	Status  string `db:"status"`
+	Count   int64  `db:"count"`
+}
+
+// Counts one notification against the caller's daily and monthly usage of a
+// channel within the context's tenant. Nothing is counted if that would exceed a limit (0 means unlimited).
+func (d *DB) ConsumeQuota(
+	ctx context.Context,
+	caller, channel string,
+	now time.Time,
+	dailyLimit, monthlyLimit int,
+) (QuotaUsage, error) {
+	var usage QuotaUsage
+	day := now.UTC().Format("2006-01-02")
+	tenantID := tenant.FromContext(ctx)
+
+	tx, err := d.Conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return usage, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	// The row lock on today's counter serializes concurrent checks
+	upsert := `
+		INSERT INTO usage_counters (tenant_id, caller, channel, day, count)
+		VALUES ($1, $2, $3, $4, 1)
+		ON CONFLICT (tenant_id, caller, channel, day)
+		DO UPDATE SET count = usage_counters.count + 1
+		RETURNING count`
+
+	if err := tx.QueryRowContext(ctx, upsert, tenantID, caller, channel, day).Scan(&usage.Daily); err != nil {
+		return usage, rollback(tx, fmt.Errorf("failed to count usage: %w", err))
+	}
+
+	monthly := `
+		SELECT COALESCE(SUM(count), 0)
+		FROM usage_counters
+		WHERE tenant_id = $1 AND caller = $2 AND channel = $3
+			AND day >= date_trunc('month', $4::DATE) AND day <= $4::DATE`
+
+	if err := tx.QueryRowContext(ctx, monthly, tenantID, caller, channel, day).Scan(&usage.Monthly); err != nil {
+		return usage, rollback(tx, fmt.Errorf("failed to sum monthly usage: %w", err))
+	}
+
+	if (dailyLimit > 0 && usage.Daily > dailyLimit) || (monthlyLimit > 0 && usage.Monthly > monthlyLimit) {
+		// Report usage without this request, which is not counted
+		usage.Daily--
+		usage.Monthly--
+		return usage, rollback(tx, nil)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return usage, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	usage.Allowed = true
+	return usage, nil
+}
+
+// Takes back one notification counted by ConsumeQuota at the given time, for
+// a request that failed after its quota was consumed
+func (d *DB) RefundQuota(ctx context.Context, caller, channel string, consumedAt time.Time) error {
+	query := `
+		UPDATE usage_counters
+		SET count = count - 1
+		WHERE tenant_id = $1 AND caller = $2 AND channel = $3 AND day = $4 AND count > 0`
+
+	day := consumedAt.UTC().Format("2006-01-02")
+	if _, err := d.Conn.ExecContext(ctx, query, tenant.FromContext(ctx), caller, channel, day); err != nil {
+		return fmt.Errorf("failed to refund usage: %w", err)
+	}
+
+	return nil
+}
+
+// Counts the context tenant's notifications created in [from, to) by caller,
+// channel and status.
+// An empty caller returns every caller.
+func (d *DB) GetUsage(ctx context.Context, caller string, from, to time.Time) ([]UsageRecord, error) {
+	records := []UsageRecord{}
+	query := `
+		SELECT COALESCE(caller, '') AS caller,
+			COALESCE(type, '') AS channel,
+			COALESCE(status, '') AS status,
+			COUNT(*) AS count
+		FROM notifications
+		WHERE created_at >= $1 AND created_at < $2
+			AND ($3 = '' OR caller = $3)
+			AND tenant_id = $4
+		GROUP BY 1, 2, 3
+		ORDER BY 1, 2, 3`
+
+	if err := d.Conn.SelectContext(ctx, &records, query, from, to, caller, tenant.FromContext(ctx)); err != nil {
+		return nil, fmt.Errorf("failed to get usage: %w", err)
+	}
+
+	return records, nil
+}
+
+// Rolls back tx, keeping err as the original error
+func rollback(tx *sqlx.Tx, err error) error {
+	if rbErr := tx.Rollback(); rbErr != nil {
+		if err == nil {
+			return fmt.Errorf("rollback error: %w", rbErr)
+		}
+		return fmt.Errorf("rollback error: %v (original error: %w)", rbErr, err)
+	}
+	return err
+}
@@ -2,20 +2,34 @@ package service
 
 import (
 	"bytes"
+	"context"
 	"fmt"
-	"log"
 	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/officiallysidsingh/go-notify/internal/logging"
 )
 
-func SendPushNotification(topic, title, priority, message string) error {
+// Used when a tenant has no ntfy server of its own
+const defaultNtfyServer = "https://ntfy.sh"
+
+func SendPushNotification(ctx context.Context, server, token, topic, title, priority, message string) error {
+	// - ctx: cancels the call, and carries the logger of the notification
+	// - server: ntfy server URL, defaults to ntfy.sh
+	// - token: ntfy access token, sent when not empty
 	// - topic: ntfy topic
 	// - title: title for the notification
 	// - priority: priority of notification(1 - 5)
 	// - message: the notification body
 
-	url := fmt.Sprintf("https://ntfy.sh/%s", topic)
+	if server == "" {
+		server = defaultNtfyServer
+	}
+	url := fmt.Sprintf("%s/%s", strings.TrimRight(server, "/"), topic)
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer([]byte(message)))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer([]byte(message)))
 	if err != nil {
 		return err
 	}
@@ -23,6 +37,9 @@ func SendPushNotification(topic, title, priority, message string) error {
 	req.Header.Set("Title", title)
 	req.Header.Set("X-Priority", priority)
 	req.Header.Set("Content-Type", "text/plain")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
 
 	client := &http.Client{}
 
@@ -33,10 +50,17 @@ func SendPushNotification(topic, title, priority, message string) error {
 
 	defer func() {
 		if err := res.Body.Close(); err != nil {
-			log.Printf("error closing response body: %v", err)
+			logging.FromContext(ctx, zap.NewNop()).Error("error closing response body", zap.Error(err))
 		}
 	}()
 
+	if res.StatusCode == http.StatusTooManyRequests {
+		return &RateLimitedError{
+			Provider:   "ntfy",
+			RetryAfter: parseRetryAfter(res.Header.Get("Retry-After")),
+		}
+	}
+
 	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusAccepted {
 		return fmt.Errorf("ailed to send push notification, status code: %d", res.StatusCode)
 	}
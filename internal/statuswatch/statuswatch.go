@@ -0,0 +1,142 @@
+package statuswatch
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// Postgres channel the notifications trigger publishes status changes on
+const channel = "notification_status"
+
+// Update is a status change of one notification. Resync is set instead when
+// the listener reconnected and changes may have been missed.
+type Update struct {
+	ID           int64  `json:"id"`
+	TenantID     string `json:"tenant_id"`
+	Status       string `json:"status"`
+	StatusReason string `json:"status_reason"`
+	Resync       bool   `json:"-"`
+}
+
+// Hub listens for status changes in Postgres and fans them out to watchers
+type Hub struct {
+	listener *pq.Listener
+	log      *zap.Logger
+
+	mu       sync.Mutex
+	watchers map[int64]map[chan Update]struct{}
+}
+
+// Connects a dedicated listener connection to Postgres
+func NewHub(dataSourceName string, logger *zap.Logger) (*Hub, error) {
+	h := &Hub{
+		log:      logger,
+		watchers: make(map[int64]map[chan Update]struct{}),
+	}
+
+	h.listener = pq.NewListener(dataSourceName, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			logger.Warn("Status listener event", zap.Int("event", int(event)), zap.Error(err))
+		}
+	})
+	if err := h.listener.Listen(channel); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// Delivers status changes to watchers until ctx is done
+func (h *Hub) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			if err := h.listener.Close(); err != nil {
+				h.log.Error("error closing status listener", zap.Error(err))
+			}
+			return
+		case n := <-h.listener.Notify:
+			// A nil notification means the connection was re-established
+			if n == nil {
+				h.broadcast(Update{Resync: true})
+				continue
+			}
+
+			var update Update
+			if err := json.Unmarshal([]byte(n.Extra), &update); err != nil {
+				h.log.Warn("Invalid status notification", zap.String("payload", n.Extra), zap.Error(err))
+				continue
+			}
+			h.deliver(update)
+		case <-time.After(90 * time.Second):
+			// Detect dead connections that never report an error
+			go func() {
+				if err := h.listener.Ping(); err != nil {
+					h.log.Warn("Status listener ping failed", zap.Error(err))
+				}
+			}()
+		}
+	}
+}
+
+// Returns a channel receiving the notification's status changes and a
+// function that stops watching
+func (h *Hub) Watch(id int64) (<-chan Update, func()) {
+	ch := make(chan Update, 8)
+
+	h.mu.Lock()
+	if h.watchers[id] == nil {
+		h.watchers[id] = make(map[chan Update]struct{})
+	}
+	h.watchers[id][ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.watchers[id], ch)
+		if len(h.watchers[id]) == 0 {
+			delete(h.watchers, id)
+		}
+	}
+}
+
+func (h *Hub) deliver(update Update) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.watchers[update.ID] {
+		send(ch, update)
+	}
+}
+
+func (h *Hub) broadcast(update Update) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, watchers := range h.watchers {
+		for ch := range watchers {
+			send(ch, update)
+		}
+	}
+}
+
+// Never blocks the listener on a slow watcher; a full buffer gets a resync
+// instead, so the watcher re-reads the current status
+func send(ch chan Update, update Update) {
+	select {
+	case ch <- update:
+	default:
+		select {
+		case <-ch:
+		default:
+		}
+		select {
+		case ch <- Update{ID: update.ID, Resync: true}:
+		default:
+		}
+	}
+}
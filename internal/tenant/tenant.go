@@ -0,0 +1,181 @@
+package tenant
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"text/template"
+
+	"github.com/officiallysidsingh/go-notify/config"
+)
+
+// Tenant used when a request doesn't name one
+const Default = "default"
+
+// Request metadata key naming the tenant
+const MetadataKey = "x-tenant-id"
+
+// Returned for tenants that are not configured
+var ErrUnknownTenant = errors.New("unknown tenant")
+
+// Returned when a tenant has no template with the requested name
+var ErrUnknownTemplate = errors.New("unknown template")
+
+// Tenant IDs are stored in keys and rows, so keep them simple
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// Ntfy server, default topic and access token for push notifications
+type Ntfy struct {
+	Server string
+	Topic  string
+	Token  string
+}
+
+// SMTP server and sender for email notifications
+type SMTP struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// SMS provider account and sender number
+type SMS struct {
+	Provider   string
+	AccountSID string
+	AuthToken  string
+	From       string
+}
+
+// Tenant is one product served by the deployment, with its own
+// channel credentials and notification templates
+type Tenant struct {
+	ID   string
+	Ntfy Ntfy
+	SMTP SMTP
+	SMS  SMS
+
+	// Signs status callbacks; callbacks are refused without one
+	CallbackSecret string
+
+	templates map[string]*template.Template
+}
+
+// Renders the named title and message templates with the given data
+func (t *Tenant) Render(name string, data map[string]string) (string, string, error) {
+	title, ok := t.templates[name+".title"]
+	if !ok {
+		return "", "", fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+	}
+	message := t.templates[name+".message"]
+
+	var titleBuf, messageBuf bytes.Buffer
+	if err := title.Execute(&titleBuf, data); err != nil {
+		return "", "", fmt.Errorf("failed to render template %s title: %w", name, err)
+	}
+	if err := message.Execute(&messageBuf, data); err != nil {
+		return "", "", fmt.Errorf("failed to render template %s message: %w", name, err)
+	}
+	return titleBuf.String(), messageBuf.String(), nil
+}
+
+// Registry holds the configured tenants
+type Registry struct {
+	tenants map[string]*Tenant
+}
+
+// Builds the registry from config. The default tenant always exists and
+// falls back to the global ntfy topic and callback secret.
+func NewRegistry(cfg *config.Config) (*Registry, error) {
+	r := &Registry{tenants: make(map[string]*Tenant, len(cfg.Tenants)+1)}
+
+	for _, tc := range cfg.Tenants {
+		if !idPattern.MatchString(tc.ID) {
+			return nil, fmt.Errorf("invalid tenant id %q", tc.ID)
+		}
+		if _, exists := r.tenants[tc.ID]; exists {
+			return nil, fmt.Errorf("duplicate tenant %q", tc.ID)
+		}
+
+		t := &Tenant{
+			ID:             tc.ID,
+			CallbackSecret: tc.CallbackSecret,
+			Ntfy: Ntfy{
+				Server: tc.Ntfy.Server,
+				Topic:  tc.Ntfy.Topic,
+				Token:  tc.Ntfy.Token,
+			},
+			SMTP: SMTP{
+				Host:     tc.SMTP.Host,
+				Port:     tc.SMTP.Port,
+				Username: tc.SMTP.Username,
+				Password: tc.SMTP.Password,
+				From:     tc.SMTP.From,
+			},
+			SMS: SMS{
+				Provider:   tc.SMS.Provider,
+				AccountSID: tc.SMS.AccountSID,
+				AuthToken:  tc.SMS.AuthToken,
+				From:       tc.SMS.From,
+			},
+			templates: make(map[string]*template.Template, 2*len(tc.Templates)),
+		}
+
+		for name, tmpl := range tc.Templates {
+			for part, text := range map[string]string{"title": tmpl.Title, "message": tmpl.Message} {
+				key := name + "." + part
+				parsed, err := template.New(key).Option("missingkey=zero").Parse(text)
+				if err != nil {
+					return nil, fmt.Errorf("invalid template %s for tenant %s: %w", key, tc.ID, err)
+				}
+				t.templates[key] = parsed
+			}
+		}
+
+		r.tenants[t.ID] = t
+	}
+
+	def, ok := r.tenants[Default]
+	if !ok {
+		def = &Tenant{ID: Default, templates: map[string]*template.Template{}}
+		r.tenants[Default] = def
+	}
+	if def.Ntfy.Topic == "" {
+		def.Ntfy.Topic = cfg.Ntfy.Topic
+	}
+	if def.CallbackSecret == "" {
+		def.CallbackSecret = cfg.Callback.Secret
+	}
+
+	return r, nil
+}
+
+// Returns the tenant with the given ID, or the default tenant for ""
+func (r *Registry) Get(id string) (*Tenant, error) {
+	if id == "" {
+		id = Default
+	}
+	t, ok := r.tenants[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTenant, id)
+	}
+	return t, nil
+}
+
+type contextKey struct{}
+
+// Returns a copy of ctx scoped to the tenant
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// Returns the tenant ctx is scoped to, or the default tenant
+func FromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(contextKey{}).(string); ok && id != "" {
+		return id
+	}
+	return Default
+}
@@ -0,0 +1,174 @@
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// How long a concurrency slot is held if a worker dies without releasing it
+const leaseTTL = 30 * time.Second
+
+// How long to wait before retrying when every concurrency slot is taken
+const slotRetry = 100 * time.Millisecond
+
+// Limit paces outbound calls to one provider channel.
+// Zero values disable the corresponding limit.
+type Limit struct {
+	Rate        int           // Calls allowed per Per
+	Per         time.Duration // Period for Rate
+	Concurrency int           // Calls in flight at once
+}
+
+// Throttle enforces per-channel outbound limits shared by all worker
+// replicas through Redis, and pauses channels the provider has throttled.
+type Throttle struct {
+	client *redis.Client
+	limits map[string]Limit
+}
+
+// Creates a new Throttle
+func NewThrottle(addr string, limits map[string]Limit) *Throttle {
+	client := redis.NewClient(&redis.Options{
+		Addr: addr,
+	})
+
+	return &Throttle{
+		client: client,
+		limits: limits,
+	}
+}
+
+// Tries to take a call slot for the channel. When allowed, release must be
+// called once the provider call finishes. Otherwise wait says how long until
+// the channel may have capacity again.
+func (t *Throttle) Acquire(ctx context.Context, channel string) (release func(), wait time.Duration, err error) {
+	limit := t.limits[channel]
+
+	var refillMs float64
+	if limit.Rate > 0 && limit.Per > 0 {
+		refillMs = float64(limit.Per.Milliseconds()) / float64(limit.Rate)
+	}
+
+	now := time.Now()
+	member := strconv.FormatInt(now.UnixNano(), 10) + "-" + strconv.Itoa(rand.Int())
+	slotsKey := "outbound:" + channel + ":slots"
+
+	reply, err := acquireScript.Run(
+		ctx,
+		t.client,
+		[]string{
+			"outbound:" + channel + ":pause",
+			"outbound:" + channel + ":bucket",
+			slotsKey,
+		},
+		now.UnixMilli(),
+		refillMs,
+		limit.Rate,
+		limit.Concurrency,
+		leaseTTL.Milliseconds(),
+		member,
+	).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 2 {
+		return nil, 0, fmt.Errorf("unexpected throttle script reply: %v", reply)
+	}
+	if allowed, _ := values[0].(int64); allowed != 1 {
+		waitMs, _ := values[1].(int64)
+		if waitMs < 0 {
+			return nil, slotRetry, nil
+		}
+		return nil, time.Duration(waitMs) * time.Millisecond, nil
+	}
+
+	release = func() {
+		if limit.Concurrency <= 0 {
+			return
+		}
+
+		// The caller's context may already be done
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		t.client.ZRem(ctx, slotsKey, member)
+	}
+	return release, 0, nil
+}
+
+// Stops all calls to the channel for d, e.g. after a provider's Retry-After
+func (t *Throttle) Pause(ctx context.Context, channel string, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	key := "outbound:" + channel + ":pause"
+
+	// Never shorten a longer pause set by another worker
+	current, err := t.client.PTTL(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	if current >= d {
+		return nil
+	}
+
+	return t.client.Set(ctx, key, "1", d).Err()
+}
+
+// Checks the pause flag, a free concurrency slot and a rate token, in that
+// order, only consuming a token and a slot when all three pass.
+// Returns {allowed, wait_ms}; wait_ms is -1 when waiting on a slot.
+// KEYS: pause, bucket, slots. ARGV: now, refill_ms, rate, concurrency, lease_ms, member
+var acquireScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local refill = tonumber(ARGV[2])
+local capacity = tonumber(ARGV[3])
+local concurrency = tonumber(ARGV[4])
+local lease = tonumber(ARGV[5])
+
+local paused = redis.call('PTTL', KEYS[1])
+if paused > 0 then
+	return {0, paused}
+end
+
+if concurrency > 0 then
+	redis.call('ZREMRANGEBYSCORE', KEYS[3], '-inf', now - lease)
+	if redis.call('ZCARD', KEYS[3]) >= concurrency then
+		return {0, -1}
+	end
+end
+
+if refill > 0 and capacity > 0 then
+	local state = redis.call('HMGET', KEYS[2], 'tokens', 'ts')
+	local tokens = tonumber(state[1])
+	local ts = tonumber(state[2])
+	if tokens == nil or ts == nil then
+		tokens = capacity
+		ts = now
+	end
+	tokens = math.min(capacity, tokens + math.max(now - ts, 0) / refill)
+
+	if tokens < 1 then
+		redis.call('HSET', KEYS[2], 'tokens', tostring(tokens), 'ts', now)
+		redis.call('PEXPIRE', KEYS[2], math.ceil(capacity * refill))
+		return {0, math.ceil((1 - tokens) * refill)}
+	end
+
+	redis.call('HSET', KEYS[2], 'tokens', tostring(tokens - 1), 'ts', now)
+	redis.call('PEXPIRE', KEYS[2], math.ceil(capacity * refill))
+end
+
+if concurrency > 0 then
+	redis.call('ZADD', KEYS[3], now, ARGV[6])
+	redis.call('PEXPIRE', KEYS[3], lease)
+end
+
+return {1, 0}
+`)
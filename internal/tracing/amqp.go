@@ -0,0 +1,48 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel"
+)
+
+// Carries trace context in AMQP message headers
+type amqpHeaders amqp.Table
+
+func (h amqpHeaders) Get(key string) string {
+	value, ok := h[key].(string)
+	if !ok {
+		return ""
+	}
+	return value
+}
+
+func (h amqpHeaders) Set(key, value string) {
+	h[key] = value
+}
+
+func (h amqpHeaders) Keys() []string {
+	keys := make([]string, 0, len(h))
+	for key := range h {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Returns headers carrying the context's trace, for a message published under it
+func InjectAMQP(ctx context.Context, headers amqp.Table) amqp.Table {
+	if headers == nil {
+		headers = amqp.Table{}
+	}
+	otel.GetTextMapPropagator().Inject(ctx, amqpHeaders(headers))
+	return headers
+}
+
+// Returns ctx joined to the trace carried in a delivery's headers
+func ExtractAMQP(ctx context.Context, headers amqp.Table) context.Context {
+	if headers == nil {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, amqpHeaders(headers))
+}